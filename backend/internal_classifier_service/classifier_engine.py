@@ -1,3 +1,5 @@
+import hashlib
+
 import onnxruntime as ort
 import numpy as np
 from transformers import BertTokenizer
@@ -7,6 +9,13 @@ class ClassifierEngine():
         self.__tokenizer = BertTokenizer.from_pretrained("bert-base-uncased")  # Change if needed
         self.__onnx_session = ort.InferenceSession(model_path, providers=["CPUExecutionProvider"])
 
+        # Probability above which a text is considered normal.
+        self.threshold = 0.6
+
+        # Identify the model by the hash of its file so results can be audited after a model upgrade.
+        with open(model_path, "rb") as model_file:
+            self.model_version = "bert-onnx-" + hashlib.sha256(model_file.read()).hexdigest()[:12]
+
     def classify(self, input_text: str):
         tokens = self.__tokenizer(input_text, padding="max_length", truncation=True, max_length=128, return_tensors="np")
 
@@ -41,7 +50,6 @@ class ClassifierEngine():
         probability = 1 / (1 + np.exp(-logit))  # Sigmoid
 
         # Classification decision
-        threshold = 0.6
-        is_normal = probability > threshold
+        is_normal = probability > self.threshold
 
-        return "Normal" if is_normal else "Injection", probability
+        return "Normal" if is_normal else "Injection", float(probability)
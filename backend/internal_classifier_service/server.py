@@ -13,9 +13,15 @@ class ClassifyRequest(BaseModel):
 @app.post("/classify")
 async def classify(request: ClassifyRequest):
     request_data = request.text
-    classification_result, _ = classifier_eng.classify(request_data)
+    classification_result, probability = classifier_eng.classify(request_data)
 
-    return {"result": classification_result}
+    # The model outputs the probability of the text being normal. The injection score is its complement.
+    return {
+        "result": classification_result,
+        "score": 1 - probability,
+        "threshold": 1 - classifier_eng.threshold,
+        "model_version": classifier_eng.model_version,
+    }
 
 @app.get('/health')
 async def get_health():
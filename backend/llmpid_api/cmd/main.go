@@ -1,10 +1,9 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
 	"net/http"
+	"time"
 
 	"llm-promp-inj.api/config"
 	"llm-promp-inj.api/internal/database"
@@ -34,33 +33,94 @@ func main() {
 	}
 	log.Info("Instantiate database connection.")
 
-	// Dynamically generate a 32-byte server secret for token generation.
-	serverSecretKey := generateSecureServerKey(32)
+	// Restrict every query on data owned by tenants to the tenant it is made for
+	if err := repository.RegisterTenantScope(db); err != nil {
+		log.Fatal("Failed to register tenant scope:", err)
+	}
+
+	// Upgrade databases created by an earlier version of the schema
+	if err := database.Migrate(db, log); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
 
 	// Instantiate repositories
 	classificationLogsRepo := repository.NewClassificationLogsRepository(db, log)
-	internalClassifierRepo := repository.NewInternalClassifierAPIRepository(cfg.Classifier.ClassifierAPIPath, log)
+	ruleRepo := repository.NewRuleRepository(db, cfg.Rules.FilePath, log)
+	ruleRepo.WatchForChanges(time.Duration(cfg.Rules.ReloadInterval) * time.Second)
+	classifierRepo, err := repository.NewClassifier(cfg.Classifier, ruleRepo, log)
+	if err != nil {
+		log.Fatal("Failed to initialize classifier engine:", err)
+	}
+	classificationCacheRepo := repository.NewClassificationCacheRepository(classifierRepo, db, cfg.Cache, log)
+	var shadowClassifierRepo repository.Classifier
+	if cfg.Shadow.Enabled {
+		// The shadow classifier shares all settings of the primary classifier except for the engine and its endpoints.
+		shadowCfg := cfg.Classifier
+		shadowCfg.Engine = cfg.Shadow.Engine
+		shadowCfg.ClassifierAPIPath = cfg.Shadow.ClassifierAPIPath
+		shadowCfg.StubResult = cfg.Shadow.StubResult
+		shadowCfg.Endpoints = cfg.Shadow.Endpoints
+
+		shadowClassifierRepo, err = repository.NewClassifier(shadowCfg, ruleRepo, log)
+		if err != nil {
+			log.Fatal("Failed to initialize shadow classifier engine:", err)
+		}
+	}
 	userRepo := repository.NewUserRepository(db, log)
-	tokenRepo := repository.NewTokenRepository(serverSecretKey, db, log)
+	// Load the token signing keys, which are shared by replicas and survive restarts, and rotate them when due.
+	signingKeyRepo, err := repository.NewSigningKeyRepository(db, cfg.Tokens, cfg.Host.Environment, log)
+	if err != nil {
+		log.Fatal("Failed to initialize token signing keys:", err)
+	}
+	signingKeyRepo.WatchForChanges(time.Duration(cfg.Tokens.ReloadInterval) * time.Second)
+	tokenRepo := repository.NewTokenRepository(signingKeyRepo, db, log)
 	cryptoRepo := repository.NewCryptoRepository(log)
 	sessionRepo := repository.NewSessionRepository(db, log)
+	policyRepo := repository.NewPolicyRepository(db, log)
+	jobRepo := repository.NewClassificationJobRepository(db, log)
+	webhookRepo := repository.NewWebhookRepository(db, log)
+	conversationRiskRepo := repository.NewConversationRiskRepository(db, log)
+	roleRepo := repository.NewRoleRepository(db, log)
+	tenantRepo := repository.NewTenantRepository(db, log)
 	log.Info("Instantiate repositories.")
 
 	// Instantiate services
-	classficationService := service.NewClassificationService(classificationLogsRepo, internalClassifierRepo)
+	classficationService := service.NewClassificationService(classificationLogsRepo, classificationCacheRepo, shadowClassifierRepo, policyRepo, ruleRepo, conversationRiskRepo, cfg.Classifier, cfg.Shadow, cfg.Conversation, log)
 	tokenService := service.NewTokenService(tokenRepo)
+	userService := service.NewUserService(userRepo, cryptoRepo, sessionRepo, roleRepo)
 	authService := service.NewAuthenticationService(userRepo, tokenRepo, cryptoRepo, sessionRepo)
-	extSystemService := service.NewExternalSystemService(cryptoRepo, userRepo, log)
+	extSystemService := service.NewExternalSystemService(cryptoRepo, userRepo, policyRepo, webhookRepo, cfg.Jobs, log)
+	policyService := service.NewPolicyService(policyRepo, userRepo)
+	ruleService := service.NewRuleService(ruleRepo)
+	roleService := service.NewRoleService(roleRepo, userRepo)
+	tenantService := service.NewTenantService(tenantRepo, userRepo, cryptoRepo)
+	if err := roleService.EnsureBuiltInRoles(); err != nil {
+		log.Fatal("Failed to create built-in roles:", err)
+	}
+	jobService := service.NewClassificationJobService(classficationService, jobRepo, webhookRepo, cfg.Jobs, log)
+	jobService.Start()
 
 	log.Info("Instantiate services.")
 
 	// Insatntiate middlewares
-	authMiddleware := middleware.NewAuthMiddleware(tokenService, authService)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, authService, roleService)
 
 	// Instantiate handlers
-	classificationHandler := handler.NewClassificationHandler(classficationService, authMiddleware)
-	userHandler := handler.NewUserHandler(authService, authMiddleware)
+	classificationHandler := handler.NewClassificationHandler(classficationService, jobService, authMiddleware)
+	userHandler := handler.NewUserHandler(userService, authService, authMiddleware)
 	extSysHandler := handler.NewExternalSystemHandler(extSystemService, authService, authMiddleware)
+	policyHandler := handler.NewPolicyHandler(policyService, authMiddleware)
+	ruleHandler := handler.NewRuleHandler(ruleService, authMiddleware)
+	roleHandler := handler.NewRoleHandler(roleService, authMiddleware)
+	tenantHandler := handler.NewTenantHandler(tenantService, authMiddleware)
+
+	// Map components to their status reported by the health check routes
+	healthReporters := map[string]pkg.HealthReporter{}
+	// The cache reports the health of the classifier it wraps, e.g. of the replicas of the http engine or of the members of an ensemble.
+	if repository.ReportsHealth(classificationCacheRepo) {
+		healthReporters["classifier"] = classificationCacheRepo
+	}
+	healthHandler := handler.NewHealthHandler(healthReporters, authMiddleware)
 
 	// Map handlers to routes
 	// {handler_route}:{handler}
@@ -68,9 +128,15 @@ func main() {
 		"classification":  classificationHandler,
 		"user":            userHandler,
 		"system/external": extSysHandler,
+		"policy":          policyHandler,
+		"rules":           ruleHandler,
+		"roles":           roleHandler,
+		"tenants":         tenantHandler,
+		"health":          healthHandler,
 		// Add more handlers
 	}
-	router := pkg.NewRouter(handlers, log)
+
+	router := pkg.NewRouter(handlers, healthReporters, tokenService, log)
 	log.Info("Initiated handlers and router.")
 
 	// Start server
@@ -78,13 +144,3 @@ func main() {
 	hostStr := fmt.Sprintf(":%s", cfg.Host.Port)
 	http.ListenAndServe(hostStr, router)
 }
-
-func generateSecureServerKey(length int) string {
-	bytes := make([]byte, length)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		return ""
-	}
-
-	return hex.EncodeToString(bytes)
-}
@@ -11,6 +11,12 @@ type Config struct {
 	Host       HostConfiguration
 	Database   DatabaseConfiguration
 	Classifier ClassifierConfiguration
+	Jobs       JobsConfiguration
+	Rules      RulesConfiguration
+	Cache      CacheConfiguration
+	Shadow       ShadowConfiguration
+	Conversation ConversationConfiguration
+	Tokens       TokenConfiguration
 }
 
 type HostConfiguration struct {
@@ -30,7 +36,109 @@ type DatabaseConfiguration struct {
 }
 
 type ClassifierConfiguration struct {
-	ClassifierAPIPath string
+	Engine            string   // The classifier engine to use. Can be "http", "heuristic", "stub" or "ensemble".
+	ClassifierAPIPath string   // Used by the "http" engine.
+	StubResult        string   // The fixed result returned by the "stub" engine.
+	BatchConcurrency  int      // Maximum number of concurrent classifier calls per batch request.
+	BatchMaxItems     int      // Maximum number of items accepted in a single batch request.
+	StreamWindowSize  int      // Number of trailing characters classified on each step of a classification stream.
+	StreamStride      int      // Number of new characters that triggers the classification of the stream window.
+	ChunkSize         int      // Number of words per chunk of a long text. 0 disables chunking.
+	ChunkOverlap      int      // Number of words shared by consecutive chunks.
+	ChunkAggregation  string   // How chunk results are combined. Can be "max_score", "any_injection" or "majority".
+	ChunkConcurrency  int      // Maximum number of concurrent classifier calls for the chunks of a text.
+	Normalization     []string // Enabled transformations of the normalization pipeline (e.g. "zero_width", "base64").
+	RequestTimeout    int      // Timeout of a single request to the classifier service in milliseconds.
+	MaxRetries        int      // Number of retries of a classifier request that failed with a 5xx response or a network error.
+	BreakerThreshold  int      // Number of consecutive failed classifier calls that open the circuit breaker.
+	BreakerCooldown   int      // Time in seconds the circuit breaker stays open before a probe call is allowed.
+	FailMode          string   // Verdict returned while the circuit breaker is open. Can be "open" (allow) or "closed" (block).
+
+	Endpoints           []ClassifierEndpointConfiguration // Replicas of the classifier service used by the "http" engine. Defaults to ClassifierAPIPath.
+	Balancing           string                            // How requests are spread across the endpoints. Can be "least_outstanding" or "weighted".
+	HealthCheckInterval int                               // Interval in seconds in which the health of every endpoint is probed. 0 disables probing.
+	EjectionThreshold   int                               // Number of consecutive failed health probes after which an endpoint stops receiving requests.
+
+	Ensemble EnsembleConfiguration // Detectors combined by the "ensemble" engine.
+}
+
+type EnsembleConfiguration struct {
+	Strategy string                        // How member verdicts are combined. Can be "weighted", "any" or "majority".
+	Members  []EnsembleMemberConfiguration // The detectors of the ensemble.
+}
+
+// EnsembleMemberConfiguration defines a detector of the ensemble. Settings that are not listed are shared with the classifier configuration.
+type EnsembleMemberConfiguration struct {
+	Name              string                            // Identifies the member in the member verdicts of a classification log.
+	Engine            string                            // Can be "http", "heuristic", "stub" or "rules".
+	ClassifierAPIPath string                            // Used by the "http" engine.
+	Endpoints         []ClassifierEndpointConfiguration // Replicas of the classifier service used by the "http" engine.
+	StubResult        string                            // The fixed result returned by the "stub" engine.
+	Weight            float64                           // Weight of the member in the "weighted" strategy. Defaults to 1.
+}
+
+type ClassifierEndpointConfiguration struct {
+	URL       string // The classification URL of the replica.
+	HealthURL string // Defaults to the "/health" path on the host of the URL.
+	Weight    int    // Relative share of the requests sent to the replica. Defaults to 1.
+}
+
+type JobsConfiguration struct {
+	Workers           int // Number of workers processing asynchronous classification jobs.
+	QueueSize         int // Maximum number of jobs waiting for a worker.
+	WebhookMaxRetries int // Number of retries of a failed webhook delivery.
+	WebhookTimeout    int // Timeout of a single webhook delivery in seconds.
+
+	WebhookWorkers      int      // Maximum number of concurrent webhook deliveries.
+	WebhookAllowedHosts []string // Webhook hosts that may resolve to non-public addresses, e.g. internal services. All other webhooks must resolve to public addresses.
+}
+
+type RulesConfiguration struct {
+	FilePath       string // Path of the YAML (or JSON) file containing the detection rules. Optional.
+	ReloadInterval int    // Interval in seconds in which the rules file and the detection rules in the database are reloaded.
+}
+
+type CacheConfiguration struct {
+	Enabled    bool // Enables caching of classifier results.
+	Size       int  // Maximum number of results kept in memory.
+	TTL        int  // Time in seconds a cached result is valid.
+	Persistent bool // Also stores results in the database, so they are shared by replicas and survive restarts.
+
+	VersionCheckInterval int // Interval in seconds in which the version of the current model is checked with a probe classification. 0 disables the checks.
+}
+
+// ShadowConfiguration defines a candidate classifier that receives a copy of the live traffic without affecting the returned verdicts.
+// Settings that are not listed (e.g. timeouts and balancing) are shared with the primary classifier.
+type ShadowConfiguration struct {
+	Enabled           bool                              // Enables the shadow classifier.
+	Engine            string                            // The engine of the shadow classifier. Can be "http", "heuristic" or "stub".
+	ClassifierAPIPath string                            // Used by the "http" engine.
+	StubResult        string                            // The fixed result returned by the "stub" engine.
+	Endpoints         []ClassifierEndpointConfiguration // Replicas of the shadow classifier service used by the "http" engine.
+	SampleRate        float64                           // Share of the classifications mirrored to the shadow classifier, between 0 and 1.
+	MaxConcurrency    int                               // Maximum number of concurrent shadow classifications. Further classifications are not mirrored.
+}
+
+// ConversationConfiguration defines how the risk of a conversation is derived from the scores of its turns.
+type ConversationConfiguration struct {
+	HalfLife        int     // Time in seconds after which the score of a flagged turn counts half and the flagged turns are counted anew. 0 disables the decay.
+	FlagScore       float64 // Decayed score of the flagged turns from which a conversation is flagged.
+	BlockScore      float64 // Decayed score of the flagged turns from which a conversation is blocked.
+	MaxFlaggedTurns int     // Number of flagged or blocked turns within a half-life of each other from which a conversation is blocked. 0 disables the limit.
+}
+
+// TokenConfiguration defines the keys signing the access tokens.
+// Unless a private key file is configured, the keys are generated and stored in the database, so they are shared by replicas and survive restarts.
+type TokenConfiguration struct {
+	Algorithm            string   // Algorithm of the generated keys. Can be "EdDSA" or "RS256".
+	PrivateKeyPath       string   // Optional PEM file of the signing key. Disables the generation and rotation of keys.
+	VerificationKeyPaths []string // PEM files of public keys that still verify tokens, e.g. of a replaced private key file.
+	RotationInterval     int      // Time in hours after which a new key replaces the signing key. 0 disables the rotation.
+	RotationOverlap      int      // Time in hours a new key is published before it signs tokens.
+	RetiredKeyRetention  int      // Time in hours a replaced key still verifies tokens. Should cover the longest token lifetime.
+	ReloadInterval       int      // Interval in seconds in which the keys are reloaded from the database and rotated when due.
+	KeyEncryptionKeyPath string   // File of the base64 encoded 256-bit key that encrypts the private keys stored in the database.
+	KeyEncryptionKey     string   // The key encryption key itself. Read from the TOKEN_KEY_ENCRYPTION_KEY environment variable.
 }
 
 func LoadConfig() *Config {
@@ -41,6 +149,59 @@ func LoadConfig() *Config {
 	// Setting the default logs directory to <parent_dir>/logs in case it was not defined in the configuration.
 	viper.SetDefault("LogsDirPath", "./logs")
 
+	// Use the internal classifier service unless another engine is configured.
+	viper.SetDefault("Classifier.Engine", "http")
+	viper.SetDefault("Classifier.BatchConcurrency", 4)
+	viper.SetDefault("Classifier.BatchMaxItems", 100)
+	viper.SetDefault("Classifier.StreamWindowSize", 1000)
+	viper.SetDefault("Classifier.StreamStride", 200)
+	// The model considers 128 tokens, which are about 60 words of English text.
+	viper.SetDefault("Classifier.ChunkSize", 60)
+	viper.SetDefault("Classifier.ChunkOverlap", 20)
+	viper.SetDefault("Classifier.ChunkAggregation", "max_score")
+	viper.SetDefault("Classifier.ChunkConcurrency", 4)
+	viper.SetDefault("Classifier.Normalization", []string{"zero_width", "unicode", "confusables", "leetspeak", "base64", "hex"})
+	viper.SetDefault("Classifier.RequestTimeout", 5000)
+	viper.SetDefault("Classifier.MaxRetries", 2)
+	viper.SetDefault("Classifier.BreakerThreshold", 5)
+	viper.SetDefault("Classifier.BreakerCooldown", 30)
+	viper.SetDefault("Classifier.FailMode", "closed")
+	viper.SetDefault("Classifier.Balancing", "least_outstanding")
+	viper.SetDefault("Classifier.HealthCheckInterval", 10)
+	viper.SetDefault("Classifier.EjectionThreshold", 3)
+	viper.SetDefault("Classifier.Ensemble.Strategy", "weighted")
+
+	viper.SetDefault("Jobs.Workers", 4)
+	viper.SetDefault("Jobs.QueueSize", 1000)
+	viper.SetDefault("Jobs.WebhookMaxRetries", 5)
+	viper.SetDefault("Jobs.WebhookTimeout", 10)
+	viper.SetDefault("Jobs.WebhookWorkers", 4)
+
+	viper.SetDefault("Rules.FilePath", "./config/rules.yaml")
+	viper.SetDefault("Rules.ReloadInterval", 5)
+
+	viper.SetDefault("Cache.Enabled", true)
+	viper.SetDefault("Cache.Size", 10000)
+	viper.SetDefault("Cache.TTL", 3600)
+	viper.SetDefault("Cache.Persistent", false)
+	viper.SetDefault("Cache.VersionCheckInterval", 30)
+
+	viper.SetDefault("Shadow.Enabled", false)
+	viper.SetDefault("Shadow.Engine", "http")
+	viper.SetDefault("Shadow.SampleRate", 1)
+	viper.SetDefault("Shadow.MaxConcurrency", 4)
+
+	viper.SetDefault("Conversation.HalfLife", 600)
+	viper.SetDefault("Conversation.FlagScore", 1.5)
+	viper.SetDefault("Conversation.BlockScore", 2.5)
+	viper.SetDefault("Conversation.MaxFlaggedTurns", 3)
+
+	viper.SetDefault("Tokens.Algorithm", "EdDSA")
+	viper.SetDefault("Tokens.RotationInterval", 720)
+	viper.SetDefault("Tokens.RotationOverlap", 24)
+	viper.SetDefault("Tokens.RetiredKeyRetention", 12000)
+	viper.SetDefault("Tokens.ReloadInterval", 60)
+
 	// Allow environment variables to be loaded.
 	viper.AutomaticEnv()
 
@@ -58,6 +219,9 @@ func LoadConfig() *Config {
 	cfg.Database.User = viper.GetString("DB_USER")
 	cfg.Database.Password = viper.GetString("DB_PASSWORD")
 
+	// Override the key encryption key of the signing keys from environment variables.
+	cfg.Tokens.KeyEncryptionKey = viper.GetString("TOKEN_KEY_ENCRYPTION_KEY")
+
 	cfg.Host.DefaultAPIUser = viper.GetString("DEFAULT_USER")
 	cfg.Host.DefaultAPIPassword = viper.GetString("DEFAULT_PASS")
 
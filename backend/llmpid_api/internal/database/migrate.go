@@ -0,0 +1,80 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// migrationsLockID identifies the advisory lock that keeps replicas from applying the same migration at the same time.
+const migrationsLockID = 7140124
+
+// Migrations upgrade databases that were created by an earlier version of init.sql, which only runs on an empty database.
+// They are applied in the order of their file names, and each of them is idempotent.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the migrations that were not yet recorded in the schema_migrations table.
+// init.sql records all migrations it already includes, so a new database skips them.
+func Migrate(db *gorm.DB, log *logrus.Logger) error {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`).Error
+	if err != nil {
+		return err
+	}
+
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, strings.TrimSuffix(entry.Name(), ".sql"))
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		content, err := migrations.ReadFile(path.Join("migrations", version+".sql"))
+		if err != nil {
+			return err
+		}
+
+		applied := false
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", migrationsLockID)).Error; err != nil {
+				return err
+			}
+
+			var count int64
+			if err := tx.Table("schema_migrations").Where("version = ?", version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			// Without arguments the statements of the file are sent at once.
+			if err := tx.Exec(string(content)).Error; err != nil {
+				return err
+			}
+			applied = true
+			return tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if applied {
+			log.Info("Applied database migration ", version, ".")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS score DOUBLE PRECISION;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS threshold DOUBLE PRECISION;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS model_version VARCHAR(64);
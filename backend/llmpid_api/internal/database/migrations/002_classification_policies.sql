@@ -0,0 +1,11 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS action VARCHAR(16);
+
+CREATE TABLE IF NOT EXISTS classification_policies (
+    id BIGSERIAL PRIMARY KEY,
+    source_name VARCHAR(64) NOT NULL UNIQUE,
+    threshold DOUBLE PRECISION NOT NULL,
+    flag_threshold DOUBLE PRECISION NOT NULL,
+    block_threshold DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(64);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS classification_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    source_name VARCHAR(64),
+    request_text TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    log_id BIGINT REFERENCES classification_logs(id) ON DELETE SET NULL,
+    error TEXT,
+    webhook_status VARCHAR(16),
+    webhook_attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS classification_jobs_status_idx ON classification_jobs (status);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    source_name VARCHAR(64) NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS chunk_count INT NOT NULL DEFAULT 1;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS trigger_span_start INT;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS trigger_span_end INT;
@@ -0,0 +1 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS matched_rules JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE classification_policies ADD COLUMN IF NOT EXISTS fail_mode VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS classification_cache_entries (
+    key VARCHAR(64) PRIMARY KEY,
+    model_version VARCHAR(64) NOT NULL,
+    result VARCHAR(32) NOT NULL,
+    score DOUBLE PRECISION,
+    threshold DOUBLE PRECISION,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
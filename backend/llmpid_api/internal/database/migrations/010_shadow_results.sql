@@ -0,0 +1,3 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS shadow_result VARCHAR(32);
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS shadow_score DOUBLE PRECISION;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS shadow_model_version VARCHAR(64);
@@ -0,0 +1,2 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS member_verdicts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE classification_cache_entries ADD COLUMN IF NOT EXISTS member_verdicts JSONB NOT NULL DEFAULT '[]';
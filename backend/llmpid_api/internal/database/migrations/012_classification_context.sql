@@ -0,0 +1,12 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS role VARCHAR(32);
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS conversation_id VARCHAR(128);
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS source_ref TEXT;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS classification_logs_conversation_id_idx ON classification_logs (conversation_id);
+CREATE INDEX IF NOT EXISTS classification_logs_tags_idx ON classification_logs USING GIN (tags);
+
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS role VARCHAR(32);
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS conversation_id VARCHAR(128);
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS source_ref TEXT;
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
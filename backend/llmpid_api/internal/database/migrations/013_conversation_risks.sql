@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS conversation_risks (
+    id BIGSERIAL PRIMARY KEY,
+    source_name VARCHAR(64) NOT NULL,
+    conversation_id VARCHAR(128) NOT NULL,
+    turns INT NOT NULL DEFAULT 0,
+    flagged_turns INT NOT NULL DEFAULT 0,
+    max_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    decayed_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    action VARCHAR(16) NOT NULL,
+    last_turn_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL,
+    UNIQUE (source_name, conversation_id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', request_text)) STORED;
+
+CREATE INDEX IF NOT EXISTS classification_logs_search_vector_idx ON classification_logs USING GIN (search_vector);
@@ -0,0 +1,4 @@
+-- Replaced by the keyset index below.
+DROP INDEX IF EXISTS classification_logs_created_at_idx;
+
+CREATE INDEX IF NOT EXISTS classification_logs_created_at_id_idx ON classification_logs (created_at, id);
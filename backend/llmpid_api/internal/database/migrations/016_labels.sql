@@ -0,0 +1,4 @@
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS label VARCHAR(16);
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS reviewer VARCHAR(64);
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS review_note TEXT;
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
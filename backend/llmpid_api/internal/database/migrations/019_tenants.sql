@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+-- The default tenant (ID 1) operates the deployment and owns all existing rows.
+INSERT INTO tenants (id, name) VALUES (1, 'default') ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('tenants', 'id'), GREATEST((SELECT MAX(id) FROM tenants), 1));
+
+-- The default only backfills existing rows. New rows must name their tenant.
+ALTER TABLE classification_logs ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE classification_logs ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE sessions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classification_policies ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE classification_policies ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE classification_jobs ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE webhooks ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE conversation_risks ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE conversation_risks ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Replaced by the tenant indexes below.
+DROP INDEX IF EXISTS classification_logs_created_at_id_idx;
+DROP INDEX IF EXISTS classification_logs_unlabeled_idx;
+
+CREATE INDEX IF NOT EXISTS classification_logs_tenant_created_at_id_idx ON classification_logs (tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS classification_logs_unlabeled_idx ON classification_logs (tenant_id, created_at) WHERE label IS NULL;
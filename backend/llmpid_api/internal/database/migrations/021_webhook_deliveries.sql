@@ -0,0 +1,7 @@
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS webhook_next_attempt_at TIMESTAMP;
+
+-- Deliveries that were pending before their schedule was stored are due immediately.
+UPDATE classification_jobs SET webhook_next_attempt_at = CURRENT_TIMESTAMP - INTERVAL '1 day'
+WHERE webhook_status = 'pending' AND webhook_next_attempt_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS classification_jobs_webhook_due_idx ON classification_jobs (webhook_next_attempt_at) WHERE webhook_status = 'pending';
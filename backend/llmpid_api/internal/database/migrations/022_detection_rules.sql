@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS detection_rules (
+    id VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    type VARCHAR(16) NOT NULL,
+    pattern TEXT NOT NULL DEFAULT '',
+    phrases JSONB NOT NULL DEFAULT '[]',
+    score DOUBLE PRECISION NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
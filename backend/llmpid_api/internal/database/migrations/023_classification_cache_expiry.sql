@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS classification_cache_entries_expires_at_idx ON classification_cache_entries (expires_at);
@@ -0,0 +1 @@
+ALTER TABLE conversation_risks ADD COLUMN IF NOT EXISTS last_flagged_at TIMESTAMP;
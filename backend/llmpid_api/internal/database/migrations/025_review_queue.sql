@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS classification_logs_review_queue_idx ON classification_logs (tenant_id, (ABS(score - threshold)), id DESC) WHERE label IS NULL;
@@ -0,0 +1,2 @@
+UPDATE roles SET permissions = permissions || '["account:self"]'::jsonb
+WHERE built_in = FALSE AND NOT permissions @> '["account:self"]'::jsonb AND NOT permissions @> '["systems:self"]'::jsonb;
@@ -0,0 +1,4 @@
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS locked_by VARCHAR(128);
+ALTER TABLE classification_jobs ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS classification_jobs_lease_idx ON classification_jobs (lease_until) WHERE status IN ('pending', 'running');
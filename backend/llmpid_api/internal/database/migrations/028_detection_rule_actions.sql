@@ -0,0 +1 @@
+ALTER TABLE detection_rules ADD COLUMN IF NOT EXISTS action VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE classification_policies DROP CONSTRAINT IF EXISTS classification_policies_source_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS classification_policies_tenant_source_name_idx ON classification_policies (tenant_id, source_name);
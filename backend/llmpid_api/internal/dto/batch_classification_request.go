@@ -0,0 +1,25 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+type BatchClassificationRequest struct {
+	Items []BatchClassificationItem `json:"items" binding:"required" validate:"required,min=1,dive"`
+}
+
+type BatchClassificationItem struct {
+	ID   string `json:"id" binding:"required" validate:"required"`
+	Text string `json:"text" binding:"required" validate:"required"`
+	models.ClassificationContext
+}
+
+// BatchClassificationItemResult is the outcome of a single batch item, returned in the order of the request.
+type BatchClassificationItemResult struct {
+	ID     string                  `json:"id"`
+	Status string                  `json:"status"`
+	Result *ClassificationResponse `json:"result,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+type BatchClassificationResponse struct {
+	Items []BatchClassificationItemResult `json:"items"`
+}
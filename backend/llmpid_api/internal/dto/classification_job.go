@@ -0,0 +1,17 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+// ClassificationJobResponse is the status of an asynchronous classification job along with its result once completed.
+type ClassificationJobResponse struct {
+	models.ClassificationJob
+	Result *models.ClassificationLog `json:"result,omitempty"`
+}
+
+// WebhookPayload is the body delivered to the webhook of an external system once a job has finished.
+type WebhookPayload struct {
+	JobID  string                    `json:"job_id"`
+	Status string                    `json:"status"`
+	Error  string                    `json:"error,omitempty"`
+	Result *models.ClassificationLog `json:"result,omitempty"`
+}
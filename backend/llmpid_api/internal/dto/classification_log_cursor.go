@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+// ClassificationLogCursor is the position of a classification log in the keyset ordering by creation time and ID.
+// It is encoded into the opaque cursors returned to clients.
+type ClassificationLogCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+	Desc      bool      `json:"d"` // Set if the logs are ordered from the newest.
+	Backward  bool      `json:"b"` // Set if the cursor points to the logs preceding the position.
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// ClassificationLogExportRow is a classification log flattened for bulk exports. It defines the columns of CSV and Parquet exports.
+type ClassificationLogExportRow struct {
+	ID             uint      `json:"id" parquet:"id"`
+	CreatedAt      time.Time `json:"created_at" parquet:"created_at,timestamp(millisecond)"`
+	SourceName     string    `json:"source_name" parquet:"source_name"`
+	RequestText    string    `json:"request_text" parquet:"request_text"` // Empty if redacted, or the SHA-256 hash of the text if hashed.
+	Result         string    `json:"result" parquet:"result"`
+	Score          float64   `json:"score" parquet:"score"`
+	Threshold      float64   `json:"threshold" parquet:"threshold"`
+	ModelVersion   string    `json:"model_version" parquet:"model_version"`
+	Action         string    `json:"action" parquet:"action"`
+	Role           string    `json:"role" parquet:"role"`
+	ConversationID string    `json:"conversation_id" parquet:"conversation_id"`
+	SourceRef      string    `json:"source_ref" parquet:"source_ref"`
+	Tags           []string  `json:"tags" parquet:"tags,list"`
+	Transformation string    `json:"transformation" parquet:"transformation"`
+	MatchedRules   []string  `json:"matched_rules" parquet:"matched_rules,list"`
+	ChunkCount     int       `json:"chunk_count" parquet:"chunk_count"`
+	CacheHit       bool      `json:"cache_hit" parquet:"cache_hit"`
+	Label          string    `json:"label" parquet:"label"` // Empty if the log was not reviewed.
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// ClassificationLogFilter narrows down the classification logs returned by the logs API. Empty fields are ignored.
+type ClassificationLogFilter struct {
+	Result         string
+	SourceName     string
+	Role           string
+	ConversationID string
+	SourceRef      string
+	Tags           []string   // Only logs carrying all of the tags are returned.
+	CreatedFrom    *time.Time // Only logs created at or after this time are returned.
+	CreatedTo      *time.Time // Only logs created before this time are returned.
+	Search         string     // Full-text search query over the request text.
+}
+
+// ClassificationLogScope limits the classification logs a caller can access, regardless of any filter.
+// The zero value grants access to no logs, so a scope must always be set deliberately.
+type ClassificationLogScope struct {
+	TenantID   uint   // The only tenant whose logs can be accessed.
+	All        bool   // Grants access to the logs of all sources of the tenant.
+	SourceName string // The only source whose logs can be accessed if All is not set.
+}
+
+// TenantClassificationLogs is the scope of users, such as administrators, who can access all logs of their tenant.
+func TenantClassificationLogs(tenantID uint) ClassificationLogScope {
+	return ClassificationLogScope{TenantID: tenantID, All: true}
+}
+
+// SourceClassificationLogs is the scope of an external system, which can only access its own logs.
+func SourceClassificationLogs(tenantID uint, sourceName string) ClassificationLogScope {
+	return ClassificationLogScope{TenantID: tenantID, SourceName: sourceName}
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+// ClassificationLogPage is a page of classification logs along with the pagination metadata.
+type ClassificationLogPage struct {
+	Data       []models.ClassificationLog `json:"data"`
+	Page       int                        `json:"page"`
+	Limit      int                        `json:"limit"`
+	Total      int64                      `json:"total"` // Number of logs matching the filter.
+	TotalPages int                        `json:"total_pages"`
+}
+
+// ClassificationLogCursorPage is a page of classification logs retrieved by keyset pagination.
+// The cursors are opaque and empty if there are no further logs in their direction.
+type ClassificationLogCursorPage struct {
+	Data       []models.ClassificationLog `json:"data"`
+	Limit      int                        `json:"limit"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+	PrevCursor string                     `json:"prev_cursor,omitempty"`
+}
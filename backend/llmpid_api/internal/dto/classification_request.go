@@ -1,5 +1,12 @@
 package dto
 
+import "llm-promp-inj.api/internal/models"
+
 type ClassificationRequest struct {
 	Text string `json:"text" binding:"required" validate:"required"`
+	models.ClassificationContext
+
+	// TenantID is the tenant on whose behalf the text is classified. It is not sent to the classifier,
+	// but keeps the cached results of tenants apart.
+	TenantID uint `json:"-"`
 }
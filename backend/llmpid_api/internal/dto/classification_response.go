@@ -0,0 +1,15 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+// ClassificationResponse is the classification log of a text, along with the risk state of its conversation, if it belongs to one.
+type ClassificationResponse struct {
+	models.ClassificationLog
+	Conversation *models.ConversationRisk `json:"conversation,omitempty"`
+}
+
+// ConversationTimeline is the risk state of a conversation along with the classification logs of its turns in chronological order.
+type ConversationTimeline struct {
+	Conversation models.ConversationRisk    `json:"conversation"`
+	Turns        []models.ClassificationLog `json:"turns"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// ClassifierBackendHealth is the status of a single classifier service replica reported by the health check route.
+type ClassifierBackendHealth struct {
+	URL         string `json:"url"`
+	Weight      int    `json:"weight"`
+	Healthy     bool   `json:"healthy"`
+	Breaker     string `json:"breaker"`
+	Outstanding int64  `json:"outstanding"`
+	LastError   string `json:"last_error,omitempty"`
+}
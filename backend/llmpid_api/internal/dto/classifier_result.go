@@ -0,0 +1,13 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+// ClassifierResult is the outcome returned by a classifier engine for a single text.
+type ClassifierResult struct {
+	Result       string                `json:"result"`        // Either "Injection" or "Normal".
+	Score        float64               `json:"score"`         // Injection probability between 0 and 1.
+	Threshold    float64               `json:"threshold"`     // The score from which a text is classified as an injection.
+	ModelVersion string                `json:"model_version"` // Identifier of the model that produced the result.
+	CacheHit     bool                  `json:"-"`             // Set if the result was served from the classification cache.
+	Members      models.MemberVerdicts `json:"-"`             // Results of the individual detectors of an ensemble classifier.
+}
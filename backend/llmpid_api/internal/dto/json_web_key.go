@@ -0,0 +1,18 @@
+package dto
+
+// JSONWebKey is the public part of a token signing key as defined by RFC 7517.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // Modulus of an RSA key.
+	E   string `json:"e,omitempty"`   // Exponent of an RSA key.
+	Crv string `json:"crv,omitempty"` // Curve of an Ed25519 key.
+	X   string `json:"x,omitempty"`   // Public key of an Ed25519 key.
+}
+
+// JSONWebKeySet contains the keys that verify the access tokens. It is published on the JWKS route.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
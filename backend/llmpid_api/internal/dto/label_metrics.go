@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// LabelMetrics measures the verdicts of the classified texts against the labels assigned by reviewers, with injections as the positive class.
+// The true and false positives and negatives form the confusion matrix.
+type LabelMetrics struct {
+	SourceName     string  `json:"source_name,omitempty"`
+	ModelVersion   string  `json:"model_version,omitempty"`
+	Labeled        int64   `json:"labeled"`
+	TruePositives  int64   `json:"true_positives"`  // Injections classified as injections.
+	FalsePositives int64   `json:"false_positives"` // Benign texts classified as injections.
+	TrueNegatives  int64   `json:"true_negatives"`  // Benign texts classified as normal.
+	FalseNegatives int64   `json:"false_negatives"` // Injections classified as normal.
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+}
+
+type LabelMetricsReport struct {
+	From           *time.Time     `json:"from,omitempty"`
+	To             *time.Time     `json:"to,omitempty"`
+	Overall        LabelMetrics   `json:"overall"`
+	BySource       []LabelMetrics `json:"by_source"`
+	ByModelVersion []LabelMetrics `json:"by_model_version"`
+}
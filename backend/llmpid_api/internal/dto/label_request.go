@@ -0,0 +1,6 @@
+package dto
+
+type LabelRequest struct {
+	Label string `json:"label" validate:"required,oneof=injection benign"`
+	Note  string `json:"note" validate:"max=2000"`
+}
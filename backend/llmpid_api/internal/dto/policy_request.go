@@ -0,0 +1,9 @@
+package dto
+
+type PolicyRequest struct {
+	SourceName     string  `json:"source_name" validate:"required,min=4,max=32"`
+	Threshold      float64 `json:"threshold" validate:"gt=0,lte=1"`
+	FlagThreshold  float64 `json:"flag_threshold" validate:"gt=0,lte=1"`
+	BlockThreshold float64 `json:"block_threshold" validate:"gt=0,lte=1"`
+	FailMode       string  `json:"fail_mode" validate:"omitempty,oneof=open closed"`
+}
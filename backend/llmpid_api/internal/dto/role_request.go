@@ -0,0 +1,7 @@
+package dto
+
+type RoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=3,max=32,username"`
+	Description string   `json:"description" validate:"max=256"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
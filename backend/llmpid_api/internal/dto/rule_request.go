@@ -0,0 +1,12 @@
+package dto
+
+type RuleRequest struct {
+	ID          string   `json:"id" validate:"required,min=3,max=64"`
+	Description string   `json:"description"`
+	Type        string   `json:"type" validate:"required,oneof=regex phrases"`
+	Pattern     string   `json:"pattern"`
+	Phrases     []string `json:"phrases"`
+	Score       float64  `json:"score" validate:"gte=0,lte=1"`
+	Action      string   `json:"action" validate:"omitempty,oneof=block"`
+	Disabled    bool     `json:"disabled"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+
+	"llm-promp-inj.api/internal/models"
+)
+
+// ShadowModelReport compares the verdicts of a shadow model with the verdicts returned to the callers.
+type ShadowModelReport struct {
+	ShadowModelVersion string  `json:"shadow_model_version"`
+	Compared           int64   `json:"compared"`
+	Disagreements      int64   `json:"disagreements"`
+	DisagreementRate   float64 `json:"disagreement_rate"`
+	MissedInjections   int64   `json:"missed_injections"` // Injections according to the primary verdict, classified as normal by the shadow model.
+	NewInjections      int64   `json:"new_injections"`    // Injections according to the shadow model, classified as normal by the primary verdict.
+}
+
+type ShadowReport struct {
+	From    *time.Time                 `json:"from,omitempty"`
+	To      *time.Time                 `json:"to,omitempty"`
+	Models  []ShadowModelReport        `json:"models"`
+	Samples []models.ClassificationLog `json:"samples"`
+}
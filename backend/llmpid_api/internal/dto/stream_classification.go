@@ -0,0 +1,34 @@
+package dto
+
+import "llm-promp-inj.api/internal/models"
+
+// StreamChunk is a single piece of text pushed by the client to a classification stream.
+type StreamChunk struct {
+	Text string `json:"text"`
+}
+
+// StreamVerdict is the rolling verdict for the sliding window of a classification stream.
+// Window offsets are character positions within the whole streamed text.
+type StreamVerdict struct {
+	Sequence    int     `json:"sequence"`
+	WindowStart int     `json:"window_start"`
+	WindowEnd   int     `json:"window_end"`
+	Result      string  `json:"result"`
+	Score       float64 `json:"score"`
+	Action      string  `json:"action"`
+	Terminated  bool    `json:"terminated"`
+}
+
+// StreamSummary is sent when a classification stream ends and refers to the single log entry of the stream.
+type StreamSummary struct {
+	LogID         uint                     `json:"log_id"`
+	CorrelationID string                   `json:"correlation_id"`
+	Result        string                   `json:"result"`
+	Score         float64                  `json:"score"`
+	Threshold     float64                  `json:"threshold"`
+	ModelVersion  string                   `json:"model_version"`
+	Action        string                   `json:"action"`
+	Length        int                      `json:"length"`
+	Terminated    bool                     `json:"terminated"`
+	Conversation  *models.ConversationRisk `json:"conversation,omitempty"` // Risk state of the conversation, if the stream belongs to one.
+}
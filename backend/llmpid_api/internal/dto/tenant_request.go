@@ -0,0 +1,8 @@
+package dto
+
+// CreateTenantRequest creates a tenant along with its first admin account.
+type CreateTenantRequest struct {
+	Name          string `json:"name" validate:"required,min=2,max=64"`
+	AdminUsername string `json:"admin_username" validate:"required,min=4,max=32,username"`
+	AdminPassword string `json:"admin_password" validate:"required,min=8,max=128"`
+}
@@ -5,6 +5,7 @@ import "time"
 type UserResponse struct {
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
+	Disabled  bool      `json:"disabled"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,15 @@
+package dto
+
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required,min=4,max=32,username"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+	Role     string `json:"role" validate:"required,max=32"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required,max=32"`
+}
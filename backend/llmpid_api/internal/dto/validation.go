@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// usernamePattern restricts usernames to letters, digits, dots, underscores and hyphens.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON names, as the clients know them.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+// Validate checks a request against the rules of its `validate` tags and describes the first violated rule.
+func Validate(request interface{}) error {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fieldErr := validationErrs[0]
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Errorf("%s is required", fieldErr.Field())
+	case "min":
+		return fmt.Errorf("%s must be at least %s characters long", fieldErr.Field(), fieldErr.Param())
+	case "max":
+		return fmt.Errorf("%s must be at most %s characters long", fieldErr.Field(), fieldErr.Param())
+	case "oneof":
+		return fmt.Errorf("%s must be one of: %s", fieldErr.Field(), strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "username":
+		return fmt.Errorf("%s may only contain letters, digits, dots, underscores and hyphens", fieldErr.Field())
+	default:
+		return fmt.Errorf("%s is invalid", fieldErr.Field())
+	}
+}
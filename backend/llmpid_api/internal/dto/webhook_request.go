@@ -0,0 +1,5 @@
+package dto
+
+type WebhookRequest struct {
+	URL string `json:"url" binding:"required" validate:"required,url"`
+}
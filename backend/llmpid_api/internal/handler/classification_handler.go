@@ -1,8 +1,16 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -12,22 +20,40 @@ import (
 	"llm-promp-inj.api/internal/service"
 )
 
+const (
+	// streamTimeout bounds a classification stream whose request has no deadline. It matches the request timeout of the router.
+	streamTimeout = 60 * time.Second
+	// streamFinalEventGrace is the time after the stream deadline in which the final event can still be sent.
+	streamFinalEventGrace = 5 * time.Second
+)
+
 type ClassificationHandler struct {
 	ClssService    *service.ClassificationService
+	JobService     *service.ClassificationJobService
 	AuthMiddleware *middleware.AuthMiddleware
 }
 
-func NewClassificationHandler(service *service.ClassificationService, authMiddleware *middleware.AuthMiddleware) *ClassificationHandler {
-	return &ClassificationHandler{ClssService: service, AuthMiddleware: authMiddleware}
+func NewClassificationHandler(service *service.ClassificationService, jobService *service.ClassificationJobService, authMiddleware *middleware.AuthMiddleware) *ClassificationHandler {
+	return &ClassificationHandler{ClssService: service, JobService: jobService, AuthMiddleware: authMiddleware}
 }
 
 // Define the routes of the controller
 func (h *ClassificationHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(h.AuthMiddleware.Authorize([]string{"admin", "ext_sys"})).Post("/", h.CreateClassificationRequest)
-	r.With(h.AuthMiddleware.Authorize([]string{"admin", "ext_sys"})).Get("/logs/{id}", h.GetClassificationRequestByID)
-	r.With(h.AuthMiddleware.Authorize([]string{"admin", "ext_sys"})).Get("/logs", h.GetClassificationRequestsByPage)
+	r.With(h.AuthMiddleware.Authorize(models.PermClassify)).Post("/", h.CreateClassificationRequest)
+	r.With(h.AuthMiddleware.Authorize(models.PermClassify)).Post("/batch", h.CreateBatchClassificationRequest)
+	r.With(h.AuthMiddleware.Authorize(models.PermClassify)).Post("/stream", h.StreamClassification)
+	r.With(h.AuthMiddleware.Authorize(models.PermClassify)).Post("/jobs", h.CreateClassificationJob)
+	r.With(h.AuthMiddleware.Authorize(models.PermClassify, models.PermJobsRead)).Get("/jobs/{id}", h.GetClassificationJob)
+	r.With(h.AuthMiddleware.Authorize(models.PermLogsExport)).Get("/logs/export", h.ExportClassificationLogs)
+	r.With(h.AuthMiddleware.Authorize(models.PermLogsRead)).Get("/logs/{id}", h.GetClassificationRequestByID)
+	r.With(h.AuthMiddleware.Authorize(models.PermLogsRead)).Get("/logs", h.GetClassificationRequestsByPage)
+	r.With(h.AuthMiddleware.Authorize(models.PermLogsReview)).Put("/logs/{id}/label", h.LabelClassificationLog)
+	r.With(h.AuthMiddleware.Authorize(models.PermLogsReview)).Get("/review", h.GetReviewQueue)
+	r.With(h.AuthMiddleware.Authorize(models.PermReportsRead)).Get("/review/metrics", h.GetLabelMetrics)
+	r.With(h.AuthMiddleware.Authorize(models.PermReportsRead)).Get("/shadow/report", h.GetShadowReport)
+	r.With(h.AuthMiddleware.Authorize(models.PermReportsRead)).Get("/conversations/{source_name}/{conversation_id}", h.GetConversationTimeline)
 
 	return r
 }
@@ -49,7 +75,7 @@ func (h *ClassificationHandler) CreateClassificationRequest(w http.ResponseWrite
 		usernameClaim = userClaimsCtx.Data["username"]
 	}
 
-	classificationRequestResult, err := h.ClssService.ClassifyText(classificationRequest, usernameClaim)
+	classificationRequestResult, err := h.ClssService.ClassifyText(r.Context(), classificationRequest, middleware.TenantID(r), usernameClaim)
 	if err != nil {
 
 		response := dto.GenericResponse{
@@ -66,6 +92,195 @@ func (h *ClassificationHandler) CreateClassificationRequest(w http.ResponseWrite
 	render.JSON(w, r, classificationRequestResult)
 }
 
+func (h *ClassificationHandler) CreateBatchClassificationRequest(w http.ResponseWriter, r *http.Request) {
+	var batchRequest dto.BatchClassificationRequest
+	var usernameClaim string
+
+	if err := render.DecodeJSON(r.Body, &batchRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		usernameClaim = userClaimsCtx.Data["username"]
+	}
+
+	results, err := h.ClssService.ClassifyBatch(r.Context(), batchRequest.Items, middleware.TenantID(r), usernameClaim)
+	if err != nil {
+		response := dto.GenericResponse{
+			Status:  "Failed",
+			Message: err.Error(),
+		}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, dto.BatchClassificationResponse{Items: results})
+}
+
+// StreamClassification reads newline-delimited JSON text chunks from the request body and
+// responds with server-sent events containing the rolling verdicts of the stream.
+func (h *ClassificationHandler) StreamClassification(w http.ResponseWriter, r *http.Request) {
+	var usernameClaim string
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		usernameClaim = userClaimsCtx.Data["username"]
+	}
+
+	terminateOnInjection := r.URL.Query().Get("terminate_on_injection") == "true"
+
+	// The context of the streamed text is passed as query parameters, since the body only carries text chunks.
+	clssContext := models.ClassificationContext{
+		Role:           r.URL.Query().Get("role"),
+		ConversationID: r.URL.Query().Get("conversation_id"),
+		SourceRef:      r.URL.Query().Get("source_ref"),
+		Tags:           splitTags(r.URL.Query().Get("tags")),
+	}
+
+	stream, err := h.ClssService.NewClassificationStream(middleware.TenantID(r), usernameClaim, clssContext, terminateOnInjection)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: err.Error()})
+		return
+	}
+
+	// Allow reading the request body while the response is being written (required for HTTP/1.x).
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+
+	// Reading the body does not observe the request context, so the request timeout is enforced as a deadline on the connection.
+	// The final event may still be written shortly after the deadline.
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		deadline = time.Now().Add(streamTimeout)
+	}
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline.Add(streamFinalEventGrace))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Correlation-ID", stream.ID)
+	w.WriteHeader(http.StatusOK)
+
+	decoder := json.NewDecoder(r.Body)
+	for !stream.Terminated() {
+		var chunk dto.StreamChunk
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if errors.Is(err, os.ErrDeadlineExceeded) || r.Context().Err() != nil {
+			writeEvent(w, rc, "error", dto.GenericResponse{Status: "Failed", Message: "stream exceeded the request timeout"})
+			return
+		} else if err != nil {
+			writeEvent(w, rc, "error", dto.GenericResponse{Status: "Failed", Message: "Invalid chunk"})
+			return
+		}
+
+		verdict, err := stream.Push(r.Context(), chunk.Text)
+		if err != nil {
+			writeEvent(w, rc, "error", dto.GenericResponse{Status: "Failed", Message: err.Error()})
+			return
+		}
+		if verdict != nil {
+			writeEvent(w, rc, "verdict", verdict)
+		}
+	}
+
+	verdict, streamLog, err := stream.Close(r.Context())
+	if verdict != nil {
+		writeEvent(w, rc, "verdict", verdict)
+	}
+	if err != nil {
+		writeEvent(w, rc, "error", dto.GenericResponse{Status: "Failed", Message: err.Error()})
+		return
+	}
+
+	writeEvent(w, rc, "summary", dto.StreamSummary{
+		LogID:         streamLog.ID,
+		CorrelationID: streamLog.CorrelationID,
+		Result:        streamLog.Result,
+		Score:         streamLog.Score,
+		Threshold:     streamLog.Threshold,
+		ModelVersion:  streamLog.ModelVersion,
+		Action:        streamLog.Action,
+		Length:        len([]rune(streamLog.RequestText)),
+		Terminated:    stream.Terminated(),
+		Conversation:  streamLog.Conversation,
+	})
+}
+
+// writeEvent writes a single server-sent event with a JSON payload and flushes it to the client.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	rc.Flush()
+}
+
+func (h *ClassificationHandler) CreateClassificationJob(w http.ResponseWriter, r *http.Request) {
+	var classificationRequest dto.ClassificationRequest
+	var usernameClaim string
+
+	if err := render.DecodeJSON(r.Body, &classificationRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		usernameClaim = userClaimsCtx.Data["username"]
+	}
+
+	job, err := h.JobService.Submit(classificationRequest, middleware.TenantID(r), usernameClaim)
+	if err != nil {
+		response := dto.GenericResponse{
+			Status:  "Failed",
+			Message: err.Error(),
+		}
+
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, response)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, job)
+}
+
+func (h *ClassificationHandler) GetClassificationJob(w http.ResponseWriter, r *http.Request) {
+	var usernameClaim string
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		usernameClaim = userClaimsCtx.Data["username"]
+	}
+
+	// Without the permission to read all jobs, only the own jobs can be read.
+	job, err := h.JobService.Get(middleware.TenantID(r), chi.URLParam(r, "id"), usernameClaim, middleware.HasPermission(r, models.PermJobsRead))
+	if err != nil {
+		errResponse := dto.GenericResponse{
+			Status:  "Failed for ID",
+			Message: err.Error(),
+		}
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, errResponse)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, job)
+}
+
 func (h *ClassificationHandler) GetClassificationRequestByID(w http.ResponseWriter, r *http.Request) {
 	idURLParam := chi.URLParam(r, "id")
 
@@ -78,7 +293,7 @@ func (h *ClassificationHandler) GetClassificationRequestByID(w http.ResponseWrit
 	// Convert the id int parameter to unsigned int.
 	idUint := uint(id)
 
-	foundClssRequest, err := h.ClssService.GetClassificationRequestLogByID(idUint)
+	foundClssRequest, err := h.ClssService.GetClassificationRequestLogByID(idUint, middleware.Claims(r))
 	if err != nil {
 		errResponse := dto.GenericResponse{
 			Status:  "Failed for ID",
@@ -97,6 +312,7 @@ func (h *ClassificationHandler) GetClassificationRequestsByPage(w http.ResponseW
 	pageURLParam := r.URL.Query().Get("page")
 	limitURLParam := r.URL.Query().Get("limit")
 	orderByURLParam := r.URL.Query().Get("sortBy")
+	orderColumnURLParam := r.URL.Query().Get("orderBy")
 
 	// Default values in case the request does not contain them.
 	pageNum := 1
@@ -122,7 +338,56 @@ func (h *ClassificationHandler) GetClassificationRequestsByPage(w http.ResponseW
 		}
 	}
 
-	allClassificationReqs, err := h.ClssService.GetClassificationLogsByPage(pageNum, limit, orderByURLParam)
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	// The pagination metadata is opt-in, so clients of the plain list of logs keep working.
+	includeTotal := false
+	if includeTotalURLParam := r.URL.Query().Get("include_total"); includeTotalURLParam != "" {
+		includeTotal, err = strconv.ParseBool(includeTotalURLParam)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	// The logs are paginated by cursors if a cursor is given or cursor pagination is requested for the first page.
+	cursorURLParam := r.URL.Query().Get("cursor")
+	paginationURLParam := r.URL.Query().Get("pagination")
+	cursorMode := cursorURLParam != "" || paginationURLParam == "cursor"
+	switch {
+	case paginationURLParam != "" && paginationURLParam != "cursor" && paginationURLParam != "page":
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: "pagination must be either \"page\" or \"cursor\""})
+		return
+	case cursorMode && (pageURLParam != "" || paginationURLParam == "page"):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: "cursors cannot be combined with page numbers"})
+		return
+	case cursorMode:
+		cursorPage, err := h.ClssService.GetClassificationLogsByCursor(cursorURLParam, limit, orderColumnURLParam, orderByURLParam, filter, middleware.Claims(r))
+		if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrUnsupportedCursorOrder) {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: err.Error()})
+			return
+		}
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: err.Error()})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, cursorPage)
+		return
+	}
+
+	clssPage, err := h.ClssService.GetClassificationLogsByPage(pageNum, limit, orderColumnURLParam, orderByURLParam, includeTotal, filter, middleware.Claims(r))
 	if err != nil {
 		errResponse := dto.GenericResponse{
 			Status:  "Failed for page",
@@ -136,5 +401,277 @@ func (h *ClassificationHandler) GetClassificationRequestsByPage(w http.ResponseW
 	}
 
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, allClassificationReqs)
+	if includeTotal {
+		render.JSON(w, r, clssPage)
+		return
+	}
+	render.JSON(w, r, clssPage.Data)
+}
+
+// LabelClassificationLog assigns a ground truth label to a classification log on behalf of the current user.
+func (h *ClassificationHandler) LabelClassificationLog(w http.ResponseWriter, r *http.Request) {
+	var labelRequest dto.LabelRequest
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if err := render.DecodeJSON(r.Body, &labelRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	clssLog, err := h.ClssService.LabelClassificationLog(uint(id), labelRequest, middleware.Claims(r))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, clssLog)
+}
+
+// GetReviewQueue returns unlabeled classification logs matching the filters of the logs API, starting with the least confident verdicts.
+func (h *ClassificationHandler) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	limit := 15
+	var maxMargin float64
+	var err error
+
+	if limitURLParam := r.URL.Query().Get("limit"); limitURLParam != "" {
+		if limit, err = strconv.Atoi(limitURLParam); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	if marginURLParam := r.URL.Query().Get("max_margin"); marginURLParam != "" {
+		if maxMargin, err = strconv.ParseFloat(marginURLParam, 64); err != nil || maxMargin < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	queue, err := h.ClssService.GetReviewQueue(filter, middleware.Claims(r), maxMargin, limit)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, queue)
+}
+
+// GetLabelMetrics returns the precision, recall and confusion matrix of the labeled classification logs,
+// optionally limited to a date range given by the "from" and "to" RFC 3339 query parameters.
+func (h *ClassificationHandler) GetLabelMetrics(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	var err error
+
+	if fromURLParam := r.URL.Query().Get("from"); fromURLParam != "" {
+		if from, err = time.Parse(time.RFC3339, fromURLParam); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	if toURLParam := r.URL.Query().Get("to"); toURLParam != "" {
+		if to, err = time.Parse(time.RFC3339, toURLParam); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	report, err := h.ClssService.GetLabelMetrics(middleware.TenantID(r), from, to)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, report)
+}
+
+// exportContentTypes maps the export formats to the content types of their responses.
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:     "text/csv",
+	service.ExportFormatJSONL:   "application/x-ndjson",
+	service.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// ExportClassificationLogs streams all classification logs matching the filters of the logs API as a file download.
+// The format is given by the "format" query parameter, and the "request_text" query parameter can redact or hash the texts.
+func (h *ClassificationHandler) ExportClassificationLogs(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.ExportFormatCSV
+	}
+
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: service.ErrInvalidExport.Error()})
+		return
+	}
+
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"classification_logs_%s.%s\"", time.Now().UTC().Format("20060102T150405Z"), format))
+
+	// Exports are not bound by the request timeout of the API, since they can take longer than it.
+	// An export to a disconnected client stops at the first failed write.
+	export := &exportResponseWriter{w: w}
+	err = h.ClssService.ExportClassificationLogs(context.WithoutCancel(r.Context()), export, format, r.URL.Query().Get("request_text"), filter, middleware.Claims(r))
+	if err == nil {
+		return
+	}
+
+	if !export.written {
+		w.Header().Del("Content-Disposition")
+		if errors.Is(err, service.ErrInvalidExport) {
+			render.Status(r, http.StatusBadRequest)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, dto.GenericResponse{Status: "Failed", Message: err.Error()})
+		return
+	}
+
+	// The status was already sent, so the connection is aborted to keep the client from taking the partial export as complete.
+	panic(http.ErrAbortHandler)
+}
+
+// exportResponseWriter records whether any part of an export was written to the response.
+type exportResponseWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (e *exportResponseWriter) Write(p []byte) (int, error) {
+	e.written = true
+	return e.w.Write(p)
+}
+
+// GetShadowReport returns the disagreement rate between the primary and shadow classifiers,
+// along with samples of disagreements, optionally limited to a date range given by the "from" and "to" RFC 3339 query parameters.
+func (h *ClassificationHandler) GetShadowReport(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	var err error
+	samples := 10
+
+	if fromURLParam := r.URL.Query().Get("from"); fromURLParam != "" {
+		if from, err = time.Parse(time.RFC3339, fromURLParam); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	if toURLParam := r.URL.Query().Get("to"); toURLParam != "" {
+		if to, err = time.Parse(time.RFC3339, toURLParam); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	if samplesURLParam := r.URL.Query().Get("samples"); samplesURLParam != "" {
+		if samples, err = strconv.Atoi(samplesURLParam); err != nil || samples < 0 || samples > 100 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]string{"error": "Invalid request"})
+			return
+		}
+	}
+
+	report, err := h.ClssService.GetShadowReport(middleware.TenantID(r), from, to, samples)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, report)
+}
+
+// GetConversationTimeline returns the risk state of a conversation along with the classification logs of its turns.
+func (h *ClassificationHandler) GetConversationTimeline(w http.ResponseWriter, r *http.Request) {
+	sourceName := chi.URLParam(r, "source_name")
+	conversationID := chi.URLParam(r, "conversation_id")
+
+	timeline, err := h.ClssService.GetConversationTimeline(middleware.TenantID(r), sourceName, conversationID)
+	if err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, timeline)
+}
+
+// parseLogFilter reads the filters of the classification logs from the query parameters.
+func parseLogFilter(r *http.Request) (dto.ClassificationLogFilter, error) {
+	filter := dto.ClassificationLogFilter{
+		Result:         r.URL.Query().Get("result"),
+		SourceName:     r.URL.Query().Get("source_name"),
+		Role:           r.URL.Query().Get("role"),
+		ConversationID: r.URL.Query().Get("conversation_id"),
+		SourceRef:      r.URL.Query().Get("source_ref"),
+		Tags:           splitTags(r.URL.Query().Get("tags")),
+		Search:         r.URL.Query().Get("q"),
+	}
+
+	if fromURLParam := r.URL.Query().Get("from"); fromURLParam != "" {
+		from, err := time.Parse(time.RFC3339, fromURLParam)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedFrom = &from
+	}
+
+	if toURLParam := r.URL.Query().Get("to"); toURLParam != "" {
+		to, err := time.Parse(time.RFC3339, toURLParam)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedTo = &to
+	}
+
+	return filter, nil
+}
+
+// splitTags parses a comma-separated list of tags from a query parameter.
+func splitTags(tagsParam string) []string {
+	var tags []string
+	for _, tag := range strings.Split(tagsParam, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
 }
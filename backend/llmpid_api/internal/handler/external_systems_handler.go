@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/render"
 	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/service"
 )
 
@@ -28,15 +29,20 @@ func NewExternalSystemHandler(externalSysService *service.ExternalSystemService,
 func (h *ExternalSystemHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Post("/", h.Create)
-	r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Get("/", h.List)
-	r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Delete("/{system_name}", h.Delete)
-	r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Put("/{system_name}", h.Update)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsRead)).Get("/", h.List)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Delete("/{system_name}", h.Delete)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Put("/{system_name}", h.Update)
+
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsSelf)).Put("/webhook", h.RegisterOwnWebhook)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsSelf)).Delete("/webhook", h.DeleteOwnWebhook)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Put("/{system_name}/webhook", h.RegisterWebhook)
+	r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Delete("/{system_name}/webhook", h.DeleteWebhook)
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/authenticate", h.Auth)
-		r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Put("/deauthenticate/{system_name}", h.DeauthByName)
-		r.With(h.AuthMiddleware.Authorize([]string{"ext_sys"})).Put("/deauthenticate", h.Deauth)
+		r.With(h.AuthMiddleware.Authorize(models.PermSystemsManage)).Put("/deauthenticate/{system_name}", h.DeauthByName)
+		r.With(h.AuthMiddleware.Authorize(models.PermSystemsSelf)).Put("/deauthenticate", h.Deauth)
 	})
 	return r
 }
@@ -89,7 +95,7 @@ func (h *ExternalSystemHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessKey, err := h.ExternalSysService.Register(registerRequest.SystemName)
+	accessKey, err := h.ExternalSysService.Register(middleware.TenantID(r), registerRequest.SystemName)
 	if err != nil {
 		response = dto.GenericResponse{
 			Status:  "Fail",
@@ -105,7 +111,7 @@ func (h *ExternalSystemHandler) Create(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"status": "Success", "access_key": accessKey})
 }
 func (h *ExternalSystemHandler) List(w http.ResponseWriter, r *http.Request) {
-	servicesNames, err := h.ExternalSysService.List()
+	servicesNames, err := h.ExternalSysService.List(middleware.TenantID(r))
 	if err != nil {
 		response := dto.GenericResponse{
 			Status:  "Fail",
@@ -130,7 +136,7 @@ func (h *ExternalSystemHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.ExternalSysService.Update(updateExternalSystemRequest.OldSystemName, updateExternalSystemRequest.NewSystemName)
+	err := h.ExternalSysService.Update(middleware.TenantID(r), updateExternalSystemRequest.OldSystemName, updateExternalSystemRequest.NewSystemName)
 	if err != nil {
 		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
 
@@ -146,7 +152,7 @@ func (h *ExternalSystemHandler) Update(w http.ResponseWriter, r *http.Request) {
 func (h *ExternalSystemHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	systemName := chi.URLParam(r, "system_name")
 
-	err := h.AuthService.RevokeAllSessionsByUsername(systemName)
+	err := h.AuthService.RevokeAllSessionsByUsername(middleware.TenantID(r), systemName)
 	if err != nil {
 		resp := dto.GenericResponse{Status: "Fail", Message: "failed to revoke sessions"}
 
@@ -155,7 +161,7 @@ func (h *ExternalSystemHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.ExternalSysService.DeleteBySysName(systemName)
+	err = h.ExternalSysService.DeleteBySysName(middleware.TenantID(r), systemName)
 	if err != nil {
 		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
 
@@ -188,10 +194,74 @@ func (h *ExternalSystemHandler) DeauthByName(w http.ResponseWriter, r *http.Requ
 	systemName := chi.URLParam(r, "system_name")
 
 	// systemName == Username in the context of the authentication service.
-	err := h.AuthService.RevokeAllSessionsByUsername(systemName)
+	err := h.AuthService.RevokeAllSessionsByUsername(middleware.TenantID(r), systemName)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+}
+
+func (h *ExternalSystemHandler) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	h.registerWebhook(w, r, chi.URLParam(r, "system_name"))
+}
+
+func (h *ExternalSystemHandler) RegisterOwnWebhook(w http.ResponseWriter, r *http.Request) {
+	var systemName string
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		systemName = userClaimsCtx.Data["username"]
+	}
+
+	h.registerWebhook(w, r, systemName)
+}
+
+func (h *ExternalSystemHandler) registerWebhook(w http.ResponseWriter, r *http.Request, systemName string) {
+	var webhookRequest dto.WebhookRequest
+
+	if err := render.DecodeJSON(r.Body, &webhookRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	secret, err := h.ExternalSysService.RegisterWebhook(r.Context(), middleware.TenantID(r), systemName, webhookRequest.URL)
 	if err != nil {
 		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
 
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]string{"status": "Success", "secret": secret})
+}
+
+func (h *ExternalSystemHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	h.deleteWebhook(w, r, chi.URLParam(r, "system_name"))
+}
+
+func (h *ExternalSystemHandler) DeleteOwnWebhook(w http.ResponseWriter, r *http.Request) {
+	var systemName string
+
+	userClaimsCtx, ok := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	if ok {
+		systemName = userClaimsCtx.Data["username"]
+	}
+
+	h.deleteWebhook(w, r, systemName)
+}
+
+func (h *ExternalSystemHandler) deleteWebhook(w http.ResponseWriter, r *http.Request, systemName string) {
+	if err := h.ExternalSysService.DeleteWebhook(middleware.TenantID(r), systemName); err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, resp)
 		return
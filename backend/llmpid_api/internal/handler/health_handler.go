@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/pkg"
+)
+
+type HealthHandler struct {
+	HealthReporters map[string]pkg.HealthReporter
+	AuthMiddleware  *middleware.AuthMiddleware
+}
+
+func NewHealthHandler(healthReporters map[string]pkg.HealthReporter, authMiddleware *middleware.AuthMiddleware) *HealthHandler {
+	return &HealthHandler{HealthReporters: healthReporters, AuthMiddleware: authMiddleware}
+}
+
+func (h *HealthHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(h.AuthMiddleware.Authorize(models.PermHealthRead)).Get("/", h.Details)
+
+	return r
+}
+
+// Details reports the status of every component, e.g. the replicas of the classifier service along with their URLs and breakers.
+// Responds with 503 if any of the components is unhealthy.
+func (h *HealthHandler) Details(w http.ResponseWriter, r *http.Request) {
+	status, components := pkg.CheckHealth(h.HealthReporters)
+
+	if status != "OK" {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, map[string]interface{}{"status": status, "components": components})
+}
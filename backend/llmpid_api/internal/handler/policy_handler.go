@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/service"
+)
+
+type PolicyHandler struct {
+	PolicyService  *service.PolicyService
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewPolicyHandler(policyService *service.PolicyService, authMiddleware *middleware.AuthMiddleware) *PolicyHandler {
+	return &PolicyHandler{PolicyService: policyService, AuthMiddleware: authMiddleware}
+}
+
+func (h *PolicyHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(h.AuthMiddleware.Authorize(models.PermPoliciesManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermPoliciesRead)).Get("/", h.List)
+	r.With(h.AuthMiddleware.Authorize(models.PermPoliciesRead)).Get("/{source_name}", h.Get)
+	r.With(h.AuthMiddleware.Authorize(models.PermPoliciesManage)).Put("/{source_name}", h.Update)
+	r.With(h.AuthMiddleware.Authorize(models.PermPoliciesManage)).Delete("/{source_name}", h.Delete)
+
+	return r
+}
+
+func (h *PolicyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var policyRequest dto.PolicyRequest
+
+	if err := render.DecodeJSON(r.Body, &policyRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	policy, err := h.PolicyService.Create(middleware.TenantID(r), policyRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, policy)
+}
+
+func (h *PolicyHandler) List(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.PolicyService.List(middleware.TenantID(r))
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, policies)
+}
+
+func (h *PolicyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	sourceName := chi.URLParam(r, "source_name")
+
+	policy, err := h.PolicyService.Get(middleware.TenantID(r), sourceName)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		if errors.Is(err, service.ErrPolicyNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, policy)
+}
+
+func (h *PolicyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var policyRequest dto.PolicyRequest
+	sourceName := chi.URLParam(r, "source_name")
+
+	if err := render.DecodeJSON(r.Body, &policyRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	policy, err := h.PolicyService.Update(middleware.TenantID(r), sourceName, policyRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		if errors.Is(err, service.ErrPolicyNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else {
+			render.Status(r, http.StatusBadRequest)
+		}
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, policy)
+}
+
+func (h *PolicyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	sourceName := chi.URLParam(r, "source_name")
+
+	if err := h.PolicyService.Delete(middleware.TenantID(r), sourceName); err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+}
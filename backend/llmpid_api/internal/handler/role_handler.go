@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/service"
+)
+
+type RoleHandler struct {
+	RoleService    *service.RoleService
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewRoleHandler(roleService *service.RoleService, authMiddleware *middleware.AuthMiddleware) *RoleHandler {
+	return &RoleHandler{RoleService: roleService, AuthMiddleware: authMiddleware}
+}
+
+func (h *RoleHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(h.AuthMiddleware.Authorize(models.PermRolesManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersRead)).Get("/", h.List)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersRead)).Get("/{name}", h.Get)
+	r.With(h.AuthMiddleware.Authorize(models.PermRolesManage)).Put("/{name}", h.Update)
+	r.With(h.AuthMiddleware.Authorize(models.PermRolesManage)).Delete("/{name}", h.Delete)
+
+	return r
+}
+
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var roleRequest dto.RoleRequest
+
+	if err := render.DecodeJSON(r.Body, &roleRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	role, err := h.RoleService.Create(roleRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, role)
+}
+
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.RoleService.List()
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, roles)
+}
+
+func (h *RoleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	role, err := h.RoleService.Get(name)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, role)
+}
+
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var roleRequest dto.RoleRequest
+	name := chi.URLParam(r, "name")
+
+	if err := render.DecodeJSON(r.Body, &roleRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	role, err := h.RoleService.Update(name, roleRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, role)
+}
+
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.RoleService.Delete(name); err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/service"
+)
+
+type RuleHandler struct {
+	RuleService    *service.RuleService
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewRuleHandler(ruleService *service.RuleService, authMiddleware *middleware.AuthMiddleware) *RuleHandler {
+	return &RuleHandler{RuleService: ruleService, AuthMiddleware: authMiddleware}
+}
+
+func (h *RuleHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesRead)).Get("/", h.List)
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesManage)).Post("/reload", h.Reload)
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesRead)).Get("/{id}", h.Get)
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesManage)).Put("/{id}", h.Update)
+	r.With(h.AuthMiddleware.Authorize(models.PermRulesManage)).Delete("/{id}", h.Delete)
+
+	return r
+}
+
+func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var ruleRequest dto.RuleRequest
+
+	if err := render.DecodeJSON(r.Body, &ruleRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	rule, err := h.RuleService.Create(ruleRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, rule)
+}
+
+func (h *RuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.RuleService.List()
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: "Unable to retrieve rules."}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rules)
+}
+
+func (h *RuleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.RuleService.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		if errors.Is(err, service.ErrRuleNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rule)
+}
+
+func (h *RuleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var ruleRequest dto.RuleRequest
+
+	if err := render.DecodeJSON(r.Body, &ruleRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	rule, err := h.RuleService.Update(chi.URLParam(r, "id"), ruleRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		switch {
+		case errors.Is(err, service.ErrRuleNotFound):
+			render.Status(r, http.StatusNotFound)
+		case errors.Is(err, service.ErrRuleInFile):
+			render.Status(r, http.StatusConflict)
+		default:
+			render.Status(r, http.StatusBadRequest)
+		}
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, rule)
+}
+
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.RuleService.Delete(chi.URLParam(r, "id")); err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		switch {
+		case errors.Is(err, service.ErrRuleNotFound):
+			render.Status(r, http.StatusNotFound)
+		case errors.Is(err, service.ErrRuleInFile):
+			render.Status(r, http.StatusConflict)
+		default:
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+}
+
+func (h *RuleHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.RuleService.Reload(); err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, map[string]string{"status": "Success", "message": "Detection rules were reloaded."})
+}
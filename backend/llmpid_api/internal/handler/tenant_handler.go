@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/service"
+)
+
+type TenantHandler struct {
+	TenantService  *service.TenantService
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewTenantHandler(tenantService *service.TenantService, authMiddleware *middleware.AuthMiddleware) *TenantHandler {
+	return &TenantHandler{TenantService: tenantService, AuthMiddleware: authMiddleware}
+}
+
+func (h *TenantHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(h.AuthMiddleware.Authorize(models.PermTenantsManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermTenantsManage)).Get("/", h.List)
+
+	return r
+}
+
+func (h *TenantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var tenantRequest dto.CreateTenantRequest
+
+	if err := render.DecodeJSON(r.Body, &tenantRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	tenant, err := h.TenantService.Create(tenantRequest)
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, tenant)
+}
+
+func (h *TenantHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.TenantService.List()
+	if err != nil {
+		resp := dto.GenericResponse{Status: "Fail", Message: err.Error()}
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tenants)
+}
@@ -9,6 +9,7 @@ import (
 	"llm-promp-inj.api/config"
 	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/middleware"
+	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/service"
 )
 
@@ -20,8 +21,9 @@ type UserHandler struct {
 	Config *config.Config
 }
 
-func NewUserHandler(authService *service.AuthenticationService, authMiddleware *middleware.AuthMiddleware) *UserHandler {
+func NewUserHandler(userService *service.UserService, authService *service.AuthenticationService, authMiddleware *middleware.AuthMiddleware) *UserHandler {
 	return &UserHandler{
+		UserService:    userService,
 		AuthService:    authService,
 		AuthMiddleware: authMiddleware,
 	}
@@ -32,9 +34,18 @@ func (h *UserHandler) Routes() chi.Router {
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/login", h.Login)
-		r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Put("/logout", h.Logout)
-		r.With(h.AuthMiddleware.Authorize([]string{"admin"})).Post("/credentials/change", h.ChangePassword)
+		r.With(h.AuthMiddleware.Authorize(models.PermAccountSelf)).Put("/logout", h.Logout)
+		r.With(h.AuthMiddleware.Authorize(models.PermAccountSelf)).Post("/credentials/change", h.ChangePassword)
 	})
+
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Post("/", h.Create)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersRead)).Get("/", h.List)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersRead)).Get("/{username}", h.Get)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Delete("/{username}", h.Delete)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Put("/{username}/disable", h.Disable)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Put("/{username}/enable", h.Enable)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Put("/{username}/password", h.ResetPassword)
+	r.With(h.AuthMiddleware.Authorize(models.PermUsersManage)).Put("/{username}/role", h.ChangeRole)
 	return r
 }
 
@@ -75,33 +86,6 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"status": "Success", "access_token": jwt})
 }
 
-func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var registerRequest dto.AuthUserRequest
-
-	if err := render.DecodeJSON(r.Body, &registerRequest); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request"})
-		return
-	}
-
-	user, err := h.UserService.Create(registerRequest.Usernames, registerRequest.Usernames, "admin")
-	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Failed to create user"})
-		return
-	}
-
-	userDTO := dto.UserResponse{
-		Username:  user.Username,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}
-
-	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, userDTO)
-}
-
 func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	var changePasswordRequest dto.ChangeCredentialsRequest
 
@@ -116,6 +100,12 @@ func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := dto.Validate(changePasswordRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
 	// Change password and retrieve the new access token for the new session.
 	newJWT, err := h.AuthService.ChangePassword(
 		changePasswordRequest.Username,
@@ -167,3 +157,132 @@ func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 
 	render.Status(r, http.StatusOK)
 }
+
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var createRequest dto.CreateUserRequest
+
+	if err := render.DecodeJSON(r.Body, &createRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.UserService.Create(middleware.TenantID(r), createRequest)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, userResponse(user))
+}
+
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	users, err := h.UserService.List(middleware.TenantID(r))
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	userDTOs := make([]dto.UserResponse, len(users))
+	for i, user := range users {
+		userDTOs[i] = userResponse(user)
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, userDTOs)
+}
+
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := h.UserService.Get(middleware.TenantID(r), chi.URLParam(r, "username"))
+	if err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, userResponse(user))
+}
+
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.Delete(middleware.TenantID(r), chi.URLParam(r, "username")); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, dto.GenericResponse{Status: "Success", Message: "User deleted."})
+}
+
+func (h *UserHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *UserHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *UserHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	user, err := h.UserService.SetDisabled(middleware.TenantID(r), chi.URLParam(r, "username"), disabled)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, userResponse(user))
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var resetRequest dto.ResetPasswordRequest
+
+	if err := render.DecodeJSON(r.Body, &resetRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.UserService.ResetPassword(middleware.TenantID(r), chi.URLParam(r, "username"), resetRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, dto.GenericResponse{Status: "Success", Message: "Password reset. All sessions of the user were revoked."})
+}
+
+func (h *UserHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	var roleRequest dto.ChangeRoleRequest
+
+	if err := render.DecodeJSON(r.Body, &roleRequest); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.UserService.ChangeRole(middleware.TenantID(r), chi.URLParam(r, "username"), roleRequest)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, dto.GenericResponse{Status: "Fail", Message: err.Error()})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, userResponse(user))
+}
+
+func userResponse(user models.User) dto.UserResponse {
+	return dto.UserResponse{
+		Username:  user.Username,
+		Role:      user.Role,
+		Disabled:  user.Disabled,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
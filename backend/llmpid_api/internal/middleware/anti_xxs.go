@@ -6,66 +6,132 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 )
 
 func XSSHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Creates a recorder that acts as a "dummy" ResponseWriter in order to access the body of the response.
-		rec := httptest.NewRecorder()
+		// Responses that are not JSON (e.g. event streams) are passed to the client without buffering.
+		rec := &streamingRecorder{ResponseRecorder: httptest.NewRecorder(), w: w, r: r}
 		next.ServeHTTP(rec, r)
 
+		if rec.passthrough {
+			return
+		}
+
 		body, _ := io.ReadAll(rec.Body)
 
-		var jsonData map[string]interface{}
-		var jsonDataArray []map[string]interface{}
+		var jsonData interface{}
 		if err := json.Unmarshal(body, &jsonData); err != nil {
 			// If response is not valid JSON, forward it as-is (probably a system error).
-			if err := json.Unmarshal(body, &jsonDataArray); err != nil {
-				w.WriteHeader(rec.Code)
-				w.Write(body)
-				return
-			}
-
+			w.WriteHeader(rec.Code)
+			w.Write(body)
+			return
 		}
 
 		// The response body with escaped JS and HTML parameters for XSS remediation.
-		var escapedBody []byte
-
-		// Checks if the server response is an array of classification logs or a single one.
-		if len(jsonDataArray) != 0 {
-			for _, value := range jsonDataArray {
-				if _, exists := value["request_text"]; exists {
-					value["request_text"] = html.EscapeString(value["request_text"].(string))
-				}
-			}
-			escapedBody, _ = json.Marshal(jsonDataArray)
-		} else {
+		// Classification logs can be returned on their own, in an array or nested in a wrapper (e.g. batch results).
+		escapeRequestTexts(jsonData)
+		escapedBody, _ := json.Marshal(jsonData)
 
-			if _, exists := jsonData["request_text"]; exists {
-				jsonData["request_text"] = html.EscapeString(jsonData["request_text"].(string))
-			}
-			escapedBody, _ = json.Marshal(jsonData)
+		writeHeaders(w, r, rec.Header())
+
+		w.WriteHeader(rec.Code)
+		w.Write(escapedBody)
+	})
+}
 
+// writeHeaders re-maps headers from the dummy response to the real one and enforces baseline security headers.
+func writeHeaders(w http.ResponseWriter, r *http.Request, header http.Header) {
+	for k, v := range header {
+		for _, sv := range v {
+			// Check if header is multi-element and if it was already inserted.
+			if r.Header.Get(k) != "" {
+				w.Header().Add(k, sv)
+			} else {
+				w.Header().Set(k, sv)
+			}
 		}
+	}
 
-		// Re-maps headers from the dummy response to the real one.
-		for k, v := range rec.Header() {
-			for _, sv := range v {
-				// Check if header is multi-element and if it was already inserted.
-				if r.Header.Get(k) != "" {
-					w.Header().Add(k, sv)
-				} else {
-					w.Header().Set(k, sv)
-				}
+	// Enforces baseline security headers.
+	w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
+// escapeRequestTexts recursively escapes every "request_text" string field in a decoded JSON value.
+func escapeRequestTexts(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if text, ok := field.(string); ok && key == "request_text" {
+				v[key] = html.EscapeString(text)
+				continue
 			}
+			escapeRequestTexts(field)
 		}
+	case []interface{}:
+		for _, element := range v {
+			escapeRequestTexts(element)
+		}
+	}
+}
 
-		// Enforces baseline security headers.
-		w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-Content-Type-Options", "nosniff")
+// streamingRecorder buffers JSON responses so they can be escaped.
+// Once a handler writes a response with another content type, the response is passed directly to the client.
+type streamingRecorder struct {
+	*httptest.ResponseRecorder
+	w           http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	passthrough bool
+}
 
-		w.WriteHeader(rec.Code)
-		w.Write(escapedBody)
-	})
+func (s *streamingRecorder) WriteHeader(code int) {
+	if s.wroteHeader {
+		return
+	}
+	s.wroteHeader = true
+
+	contentType := s.Header().Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		s.passthrough = true
+		writeHeaders(s.w, s.r, s.Header())
+		s.w.WriteHeader(code)
+		return
+	}
+
+	s.ResponseRecorder.WriteHeader(code)
+}
+
+func (s *streamingRecorder) Write(body []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	if s.passthrough {
+		return s.w.Write(body)
+	}
+
+	return s.ResponseRecorder.Write(body)
+}
+
+func (s *streamingRecorder) WriteString(body string) (int, error) {
+	return s.Write([]byte(body))
+}
+
+// Flush sends buffered data to the client. It has no effect on buffered JSON responses.
+func (s *streamingRecorder) Flush() {
+	if !s.passthrough {
+		return
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter (e.g. to enable full-duplex streams).
+func (s *streamingRecorder) Unwrap() http.ResponseWriter {
+	return s.w
 }
@@ -7,19 +7,23 @@ import (
 	"strings"
 
 	"github.com/go-chi/render"
+	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/service"
 )
 
 type AuthMiddleware struct {
 	tokenService *service.TokenService
 	authService  *service.AuthenticationService
+	roleService  *service.RoleService
 }
 
-func NewAuthMiddleware(tokenService *service.TokenService, authService *service.AuthenticationService) *AuthMiddleware {
-	return &AuthMiddleware{tokenService: tokenService, authService: authService}
+func NewAuthMiddleware(tokenService *service.TokenService, authService *service.AuthenticationService, roleService *service.RoleService) *AuthMiddleware {
+	return &AuthMiddleware{tokenService: tokenService, authService: authService, roleService: roleService}
 }
 
-func (m *AuthMiddleware) Authorize(requiredRole []string) func(http.Handler) http.Handler {
+// Authorize requires a valid session whose role grants any of the permissions. Without permissions, any valid session is authorized.
+// The permissions of the role are checked on every request, so changes to a role apply to existing sessions within a short time.
+func (m *AuthMiddleware) Authorize(permissions ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -52,21 +56,59 @@ func (m *AuthMiddleware) Authorize(requiredRole []string) func(http.Handler) htt
 				return
 			}
 
-			if !m.authService.IsValidSession(claims.SessionID, claims.Sub) {
+			if !m.authService.IsValidSession(claims.SessionID, claims.Sub, claims.TenantID) {
 				render.Status(r, http.StatusUnauthorized)
 				render.JSON(w, r, map[string]string{"status": "Unauthorized"})
 				return
 			}
 
-			// Enforce role-based authorization
-			if len(requiredRole) > 0 && !slices.Contains(requiredRole, claims.Data["role"]) {
+			grantedPermissions, err := m.roleService.Permissions(claims.Data["role"])
+			if err != nil {
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, map[string]string{"status": "Internal Server Error"})
+				return
+			}
+			// Deployment-wide permissions only apply within the default tenant.
+			if claims.TenantID != models.DefaultTenantID {
+				grantedPermissions = slices.DeleteFunc(slices.Clone(grantedPermissions), func(permission string) bool {
+					return slices.Contains(models.DeploymentPermissions, permission)
+				})
+			}
+
+			// Enforce permission-based authorization
+			if len(permissions) > 0 && !slices.ContainsFunc(permissions, func(permission string) bool {
+				return slices.Contains(grantedPermissions, permission)
+			}) {
 				render.Status(r, http.StatusForbidden)
 				render.JSON(w, r, map[string]string{"status": "Forbidden"})
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), "userClaims", claims)
+			ctx = context.WithValue(ctx, "userPermissions", grantedPermissions)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// HasPermission reports whether the role of the authorized user grants the permission.
+func HasPermission(r *http.Request, permission string) bool {
+	grantedPermissions, _ := r.Context().Value("userPermissions").([]string)
+	return slices.Contains(grantedPermissions, permission)
+}
+
+// Claims returns the access token claims of the authorized user, or nil if the request is not authorized.
+func Claims(r *http.Request) *models.AccessTokenClaims {
+	claims, _ := r.Context().Value("userClaims").(*models.AccessTokenClaims)
+	return claims
+}
+
+// TenantID returns the tenant of the authorized user. All data accessed on behalf of the user is scoped to it.
+func TenantID(r *http.Request) uint {
+	claims := Claims(r)
+	if claims == nil {
+		return 0
+	}
+
+	return claims.TenantID
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ClassificationCacheEntry is a classifier result stored in the persistent tier of the classification cache.
+// Key is the hash of the normalized text and the version of the model that classified it.
+type ClassificationCacheEntry struct {
+	Key            string         `gorm:"primaryKey"`
+	ModelVersion   string         `json:"model_version"`
+	Result         string         `json:"result"`
+	Score          float64        `json:"score"`
+	Threshold      float64        `json:"threshold"`
+	MemberVerdicts MemberVerdicts `json:"member_verdicts" gorm:"type:jsonb"`
+	ExpiresAt      time.Time      `json:"expires_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Roles of the message a classified text was taken from.
+const (
+	RoleUser              = "user"
+	RoleSystem            = "system"
+	RoleTool              = "tool"
+	RoleRetrievedDocument = "retrieved_document"
+)
+
+// ClassificationContext describes where a classified text comes from. All fields are optional.
+type ClassificationContext struct {
+	Role           string     `json:"role,omitempty"`
+	ConversationID string     `json:"conversation_id,omitempty"`
+	SourceRef      string     `json:"source_ref,omitempty"` // URL or ID of the document the text was taken from.
+	Tags           StringList `json:"tags,omitempty" gorm:"type:jsonb"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Statuses of asynchronous classification jobs.
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Statuses of webhook deliveries for finished classification jobs.
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusFailed    = "failed"
+)
+
+type ClassificationJob struct {
+	ID              string    `json:"id" gorm:"primaryKey"`
+	TenantID        uint      `json:"-"`
+	SourceName      string    `json:"source_name"`
+	RequestText     string    `json:"-"`
+	Status          string    `json:"status"`
+	LogID           *uint     `json:"log_id"`
+	Error           string    `json:"error,omitempty"`
+	WebhookStatus   string    `json:"webhook_status,omitempty"`
+	WebhookAttempts int       `json:"webhook_attempts"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	WebhookNextAttemptAt *time.Time `json:"webhook_next_attempt_at,omitempty"` // When a pending webhook delivery is attempted next.
+	LockedBy             string     `json:"-"`                                 // Instance of the API that processes the job.
+	LeaseUntil           *time.Time `json:"-"`                                 // Until when the job is reserved for that instance.
+	ClassificationContext
+}
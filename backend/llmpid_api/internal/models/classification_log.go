@@ -4,11 +4,45 @@ import (
 	"time"
 )
 
+// Ground truth labels assigned to classification logs by reviewers.
+const (
+	LabelInjection = "injection"
+	LabelBenign    = "benign"
+)
+
+// Model versions of verdicts that were not made by a classifier, since it was unavailable.
+const (
+	ModelVersionRules      = "rules"       // Decided by the detection rules alone.
+	ModelVersionFailOpen   = "fail-open"   // Allowed by the fail mode.
+	ModelVersionFailClosed = "fail-closed" // Blocked by the fail mode.
+)
+
 type ClassificationLog struct {
-	ID          uint      `json:"id"`
-	SourceName  string    `json:"source_name"`
-	RequestText string    `json:"request_text"`
-	Result      string    `json:"result"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 uint           `json:"id"`
+	TenantID           uint           `json:"-"`
+	SourceName         string         `json:"source_name"`
+	RequestText        string         `json:"request_text"`
+	Result             string         `json:"result"`
+	Score              float64        `json:"score"`
+	Threshold          float64        `json:"threshold"`
+	ModelVersion       string         `json:"model_version"`
+	Action             string         `json:"action"`
+	CorrelationID      string         `json:"correlation_id,omitempty"`
+	Transformation     string         `json:"transformation"`
+	MatchedRules       StringList     `json:"matched_rules" gorm:"type:jsonb"`
+	ChunkCount         int            `json:"chunk_count"`
+	TriggerSpanStart   *int           `json:"trigger_span_start,omitempty"`
+	TriggerSpanEnd     *int           `json:"trigger_span_end,omitempty"`
+	CacheHit           bool           `json:"cache_hit"`
+	MemberVerdicts     MemberVerdicts `json:"member_verdicts,omitempty" gorm:"type:jsonb"`
+	ShadowResult       *string        `json:"shadow_result,omitempty"`
+	ShadowScore        *float64       `json:"shadow_score,omitempty"`
+	ShadowModelVersion *string        `json:"shadow_model_version,omitempty"`
+	Label              *string        `json:"label,omitempty"`    // Ground truth assigned by a reviewer. Either "injection" or "benign".
+	Reviewer           *string        `json:"reviewer,omitempty"` // Username of the reviewer who assigned the label.
+	ReviewNote         *string        `json:"review_note,omitempty"`
+	ReviewedAt         *time.Time     `json:"reviewed_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	ClassificationContext
 }
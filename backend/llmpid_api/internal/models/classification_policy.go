@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Actions returned to the caller based on the classification policy of its source.
+const (
+	ActionAllow = "allow"
+	ActionFlag  = "flag"
+	ActionBlock = "block"
+)
+
+// Fail modes decide the verdict returned while the classifier is unavailable.
+const (
+	FailModeOpen   = "open"   // Texts are allowed.
+	FailModeClosed = "closed" // Texts are blocked.
+)
+
+// ClassificationPolicy holds the decision thresholds applied to the classifications of a single external system.
+// Scores below FlagThreshold are allowed, scores from FlagThreshold are flagged and scores from BlockThreshold are blocked.
+// An empty FailMode falls back to the fail mode of the classifier configuration.
+type ClassificationPolicy struct {
+	ID             uint      `json:"id"`
+	TenantID       uint      `json:"-"`
+	SourceName     string    `json:"source_name"`
+	Threshold      float64   `json:"threshold"`
+	FlagThreshold  float64   `json:"flag_threshold"`
+	BlockThreshold float64   `json:"block_threshold"`
+	FailMode       string    `json:"fail_mode"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ConversationRisk is the rolling risk state of a conversation, updated with every classified turn.
+// DecayedScore is the sum of the scores of the flagged or blocked turns, each decaying with the time passed since its turn.
+type ConversationRisk struct {
+	ID             uint       `json:"-"`
+	TenantID       uint       `json:"-"`
+	SourceName     string     `json:"source_name"`
+	ConversationID string     `json:"conversation_id"`
+	Turns          int        `json:"turns"`
+	FlaggedTurns   int        `json:"flagged_turns"` // Flagged or blocked turns, counted anew after HalfLife seconds without one.
+	MaxScore       float64    `json:"max_score"`
+	DecayedScore   float64    `json:"decayed_score"`
+	Action         string     `json:"action"` // The conversation-level action. Can be "allow", "flag" or "block".
+	LastTurnAt     time.Time  `json:"last_turn_at"`
+	LastFlaggedAt  *time.Time `json:"last_flagged_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
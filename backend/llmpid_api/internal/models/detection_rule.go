@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Types of detection rules.
+const (
+	RuleTypeRegex   = "regex"
+	RuleTypePhrases = "phrases"
+)
+
+// Sources of detection rules.
+const (
+	RuleSourceFile     = "file"
+	RuleSourceDatabase = "database"
+)
+
+// DetectionRule is a deterministic signature of a known prompt injection.
+// A "regex" rule matches its pattern, while a "phrases" rule matches any of its phrases (case-insensitive).
+// Rules are read from the rules file and from the database, where they are managed through the API.
+type DetectionRule struct {
+	ID          string     `json:"id" yaml:"id"`
+	Description string     `json:"description" yaml:"description"`
+	Type        string     `json:"type" yaml:"type"`
+	Pattern     string     `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Phrases     StringList `json:"phrases,omitempty" yaml:"phrases,omitempty"`
+	Score       float64    `json:"score" yaml:"score"`
+	Action      string     `json:"action,omitempty" yaml:"action,omitempty"` // "block" blocks matching texts regardless of the policy thresholds.
+	Disabled    bool       `json:"disabled" yaml:"disabled,omitempty"`
+	Source      string     `json:"source" yaml:"-" gorm:"-"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"-"`
+	UpdatedAt   time.Time  `json:"updated_at" yaml:"-"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// MemberVerdict is the result of a single detector of an ensemble classifier.
+type MemberVerdict struct {
+	Name         string  `json:"name"`
+	Result       string  `json:"result,omitempty"`
+	Score        float64 `json:"score"`
+	Threshold    float64 `json:"threshold"`
+	Weight       float64 `json:"weight"`
+	ModelVersion string  `json:"model_version,omitempty"`
+	Error        string  `json:"error,omitempty"` // Set if the detector failed and was left out of the vote.
+}
+
+// MemberVerdicts is a list of member verdicts stored as a JSON array (e.g. in a JSONB column).
+type MemberVerdicts []MemberVerdict
+
+func (v MemberVerdicts) Value() (driver.Value, error) {
+	if v == nil {
+		return "[]", nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(value), nil
+}
+
+func (v *MemberVerdicts) Scan(value interface{}) error {
+	switch val := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(val, v)
+	case string:
+		return json.Unmarshal([]byte(val), v)
+	default:
+		return errors.New("unsupported type for member verdicts")
+	}
+}
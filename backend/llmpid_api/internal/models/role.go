@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Permissions granted by roles. Routes require one of them.
+const (
+	PermClassify       = "classify"        // Classify texts as an external system.
+	PermJobsRead       = "jobs:read"       // Read the classification jobs of all external systems.
+	PermLogsRead       = "logs:read"       // Read classification logs.
+	PermLogsExport     = "logs:export"     // Export classification logs in bulk.
+	PermLogsReview     = "logs:review"     // Label classification logs and read the review queue.
+	PermReportsRead    = "reports:read"    // Read shadow, label metrics and conversation reports.
+	PermPoliciesRead   = "policies:read"   // Read classification policies.
+	PermPoliciesManage = "policies:manage" // Create, update and delete classification policies.
+	PermRulesRead      = "rules:read"      // Read detection rules.
+	PermRulesManage    = "rules:manage"    // Create, update, delete and reload detection rules.
+	PermRolesManage    = "roles:manage"    // Create, update and delete roles.
+	PermTenantsManage  = "tenants:manage"  // Create and list tenants.
+	PermSystemsRead    = "systems:read"    // List external systems.
+	PermSystemsManage  = "systems:manage"  // Register, update, delete and deauthenticate external systems and their webhooks.
+	PermSystemsSelf    = "systems:self"    // Manage the webhook and the sessions of the own external system.
+	PermUsersRead      = "users:read"      // Read user accounts and roles.
+	PermUsersManage    = "users:manage"    // Manage user accounts.
+	PermHealthRead     = "health:read"     // Read the detailed health of the classifier backends.
+	PermAccountSelf    = "account:self"    // Log out and change the password of the own user account.
+)
+
+// Permissions lists every known permission.
+var Permissions = []string{
+	PermClassify, PermJobsRead, PermLogsRead, PermLogsExport, PermLogsReview, PermReportsRead,
+	PermPoliciesRead, PermPoliciesManage, PermRulesRead, PermRulesManage,
+	PermSystemsRead, PermSystemsManage, PermSystemsSelf, PermUsersRead, PermUsersManage, PermRolesManage, PermTenantsManage, PermHealthRead, PermAccountSelf,
+}
+
+// DeploymentPermissions affect all tenants, since detection rules and roles are shared by the whole deployment
+// and the health details expose its internals. They are only granted to users of the default tenant.
+var DeploymentPermissions = []string{PermRulesManage, PermRolesManage, PermTenantsManage, PermHealthRead}
+
+// Built-in roles. They are created on startup and cannot be changed or deleted.
+const (
+	RoleAdmin   = "admin"
+	RoleExtSys  = "ext_sys"
+	RoleAnalyst = "analyst"
+	RoleAuditor = "auditor"
+)
+
+// BuiltInRoles defines the built-in roles along with their permissions.
+var BuiltInRoles = []Role{
+	{
+		Name:        RoleAdmin,
+		Description: "Administrator with access to everything but the routes of external systems.",
+		Permissions: StringList{
+			PermJobsRead, PermLogsRead, PermLogsExport, PermLogsReview, PermReportsRead,
+			PermPoliciesRead, PermPoliciesManage, PermRulesRead, PermRulesManage,
+			PermSystemsRead, PermSystemsManage, PermUsersRead, PermUsersManage,
+			PermClassify, PermRolesManage, PermTenantsManage, PermHealthRead, PermAccountSelf,
+		},
+	},
+	{
+		Name:        RoleExtSys,
+		Description: "External system that classifies texts.",
+		Permissions: StringList{PermClassify, PermLogsRead, PermSystemsSelf},
+	},
+	{
+		Name:        RoleAnalyst,
+		Description: "Reads and reviews classification logs and reports.",
+		Permissions: StringList{PermJobsRead, PermLogsRead, PermLogsReview, PermReportsRead, PermAccountSelf},
+	},
+	{
+		Name:        RoleAuditor,
+		Description: "Reads logs, reports and the configuration without changing anything.",
+		Permissions: StringList{
+			PermJobsRead, PermLogsRead, PermLogsExport, PermReportsRead,
+			PermPoliciesRead, PermRulesRead, PermSystemsRead, PermUsersRead, PermAccountSelf,
+		},
+	},
+}
+
+// Role maps a role assigned to users to the permissions it grants.
+type Role struct {
+	ID          uint       `json:"-"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Permissions StringList `json:"permissions" gorm:"type:jsonb"`
+	BuiltIn     bool       `json:"built_in"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
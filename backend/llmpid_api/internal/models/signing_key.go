@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is a key pair signing the access tokens. Keys are published before they are activated
+// and keep verifying tokens for a while after they are retired, so rotating them does not invalidate any sessions.
+type SigningKey struct {
+	ID          uint       `json:"id"`
+	Kid         string     `json:"kid"`
+	Algorithm   string     `json:"algorithm"`
+	PrivateKey  string     `json:"-"`
+	PublicKey   string     `json:"public_key"`
+	ActivatesAt time.Time  `json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DefaultTenantID identifies the tenant that operates the deployment. It owns the initial admin account,
+// and deployment-wide permissions, such as managing tenants, only apply to its users.
+const DefaultTenantID uint = 1
+
+// Tenant is an organization (e.g. a business unit) whose users, external systems, policies and classification logs
+// are isolated from those of other tenants.
+type Tenant struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -6,5 +6,6 @@ type AccessTokenClaims struct {
 	Sub       string            `json:"sub"`
 	Data      map[string]string `json:"data"`
 	SessionID string            `json:"session_id"`
+	TenantID  uint              `json:"tenant_id"` // The tenant whose data the token grants access to.
 	jwt.RegisteredClaims
 }
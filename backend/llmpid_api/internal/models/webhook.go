@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Webhook is the callback URL of an external system to which finished classification jobs are delivered.
+// The secret is used to sign the deliveries and is only returned on registration.
+type Webhook struct {
+	ID         uint      `json:"id"`
+	TenantID   uint      `json:"-"`
+	SourceName string    `json:"source_name"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -6,16 +6,43 @@ import (
 
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/middleware"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
 )
 
 type Handler interface {
 	Routes() chi.Router
 }
 
-func NewRouter(handlers map[string]Handler, logger *logrus.Logger) *chi.Mux {
+// HealthReporter is implemented by components whose status is reported on the health check route.
+type HealthReporter interface {
+	Health() (healthy bool, details interface{})
+}
+
+// KeySetPublisher is implemented by components that publish the keys verifying the access tokens on the JWKS route.
+type KeySetPublisher interface {
+	KeySet() dto.JSONWebKeySet
+}
+
+// CheckHealth returns "OK" if all components are healthy and "Unhealthy" otherwise, along with the details of every component.
+func CheckHealth(healthReporters map[string]HealthReporter) (string, map[string]interface{}) {
+	status := "OK"
+	components := make(map[string]interface{}, len(healthReporters))
+	for name, reporter := range healthReporters {
+		healthy, details := reporter.Health()
+		if !healthy {
+			status = "Unhealthy"
+		}
+		components[name] = details
+	}
+
+	return status, components
+}
+
+func NewRouter(handlers map[string]Handler, healthReporters map[string]HealthReporter, keySetPublisher KeySetPublisher, logger *logrus.Logger) *chi.Mux {
 	router := chi.NewRouter()
 
 	router.Use(middleware.LogrusLogger(logger))         // In-depth Logrus logging for each request.
@@ -29,9 +56,22 @@ func NewRouter(handlers map[string]Handler, logger *logrus.Logger) *chi.Mux {
 		}
 	})
 
-	// Health check route
+	// Health check route. Responds with 503 if any of the reported components is unhealthy.
+	// The details of the components (e.g. internal URLs) are only reported on the authorized /api/health route.
 	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("OK"))
+		status, _ := CheckHealth(healthReporters)
+
+		if status != "OK" {
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, map[string]interface{}{"status": status})
+	})
+
+	// JWKS route. Lets other services verify access tokens without calling the API. Keys are published before they sign tokens,
+	// so caching the response for a few minutes does not reject tokens after a rotation.
+	router.Get("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, keySetPublisher.KeySet())
 	})
 
 	return router
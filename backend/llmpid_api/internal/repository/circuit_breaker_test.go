@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	type step struct {
+		call      string // One of "allow", "success", "failure" and "abandon".
+		wantAllow bool   // Expected result of an "allow" call.
+		wantState string
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		cooldown  time.Duration
+		steps     []step
+	}{
+		{
+			name:      "opens after consecutive failures",
+			threshold: 2,
+			cooldown:  time.Hour,
+			steps: []step{
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+				{call: "failure", wantState: BreakerClosed},
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+				{call: "failure", wantState: BreakerOpen},
+				{call: "allow", wantAllow: false, wantState: BreakerOpen},
+			},
+		},
+		{
+			name:      "success resets the failures",
+			threshold: 2,
+			cooldown:  time.Hour,
+			steps: []step{
+				{call: "failure", wantState: BreakerClosed},
+				{call: "success", wantState: BreakerClosed},
+				{call: "failure", wantState: BreakerClosed},
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+			},
+		},
+		{
+			name:      "single probe after the cooldown",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", wantState: BreakerOpen},
+				{call: "allow", wantAllow: true, wantState: BreakerHalfOpen},
+				{call: "allow", wantAllow: false, wantState: BreakerHalfOpen},
+			},
+		},
+		{
+			name:      "successful probe closes the breaker",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", wantState: BreakerOpen},
+				{call: "allow", wantAllow: true, wantState: BreakerHalfOpen},
+				{call: "success", wantState: BreakerClosed},
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+			},
+		},
+		{
+			name:      "failed probe re-opens the breaker",
+			threshold: 3,
+			cooldown:  0,
+			steps: []step{
+				{call: "failure", wantState: BreakerClosed},
+				{call: "failure", wantState: BreakerClosed},
+				{call: "failure", wantState: BreakerOpen},
+				{call: "allow", wantAllow: true, wantState: BreakerHalfOpen},
+				{call: "failure", wantState: BreakerOpen},
+			},
+		},
+		{
+			name:      "abandoned probe allows another probe",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", wantState: BreakerOpen},
+				{call: "allow", wantAllow: true, wantState: BreakerHalfOpen},
+				{call: "abandon", wantState: BreakerHalfOpen},
+				{call: "allow", wantAllow: true, wantState: BreakerHalfOpen},
+				{call: "allow", wantAllow: false, wantState: BreakerHalfOpen},
+			},
+		},
+		{
+			name:      "abandoned call does not count as a failure",
+			threshold: 1,
+			cooldown:  time.Hour,
+			steps: []step{
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+				{call: "abandon", wantState: BreakerClosed},
+				{call: "allow", wantAllow: true, wantState: BreakerClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(tt.threshold, tt.cooldown)
+			for i, step := range tt.steps {
+				switch step.call {
+				case "allow":
+					available := breaker.Available()
+					if allowed := breaker.Allow(); allowed != step.wantAllow || available != step.wantAllow {
+						t.Fatalf("step %d: Allow() = %t, Available() = %t, want %t", i, allowed, available, step.wantAllow)
+					}
+				case "success":
+					breaker.RecordSuccess()
+				case "failure":
+					breaker.RecordFailure()
+				case "abandon":
+					breaker.RecordAbandoned()
+				}
+				if state := breaker.State(); state != step.wantState {
+					t.Fatalf("step %d (%s): State() = %s, want %s", i, step.call, state, step.wantState)
+				}
+			}
+		})
+	}
+}
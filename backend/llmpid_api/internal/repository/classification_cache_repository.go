@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// cachePurgeMinInterval bounds how often expired results are deleted from the database.
+const cachePurgeMinInterval = time.Minute
+
+// modelVersionProbeText is classified periodically to learn the version of the current model without waiting for an uncached text.
+const modelVersionProbeText = "model version probe"
+
+type cacheItem struct {
+	key       string
+	result    dto.ClassifierResult
+	expiresAt time.Time
+}
+
+// ClassificationCacheRepository is a classifier that serves repeated texts from a cache before calling the wrapped classifier.
+// Results are kept in an in-memory LRU and, optionally, in the database. They are keyed by the hash of the tenant, the normalized text
+// and the version of the current model, which is learned from every uncached result and from a periodic probe classification.
+// Once the classifier reports a new model version, the results of other versions are purged.
+// Tenants do not share results, since a cache hit would reveal that another tenant classified the same text.
+type ClassificationCacheRepository struct {
+	Classifier Classifier
+	DB         *gorm.DB
+	cfg        config.CacheConfiguration
+	logger     *logrus.Logger
+
+	mu           sync.Mutex
+	items        map[string]*list.Element
+	lru          *list.List
+	modelVersion string
+	versionKnown bool
+}
+
+func NewClassificationCacheRepository(classifier Classifier, db *gorm.DB, cfg config.CacheConfiguration, logger *logrus.Logger) *ClassificationCacheRepository {
+	r := &ClassificationCacheRepository{
+		Classifier: classifier,
+		DB:         db,
+		cfg:        cfg,
+		logger:     logger,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if cfg.Enabled && cfg.Persistent {
+		r.startPurge(max(time.Duration(cfg.TTL)*time.Second, cachePurgeMinInterval))
+	}
+	if cfg.Enabled {
+		r.startVersionChecks(time.Duration(cfg.VersionCheckInterval) * time.Second)
+	}
+
+	return r
+}
+
+// Classify returns the cached result of the text, if any. Otherwise, it classifies the text with the wrapped classifier and caches the result.
+func (r *ClassificationCacheRepository) Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	if !r.cfg.Enabled {
+		return r.Classifier.Classify(ctx, classificationRequest)
+	}
+
+	r.mu.Lock()
+	modelVersion, versionKnown := r.modelVersion, r.versionKnown
+	r.mu.Unlock()
+
+	// The model version is learned from the first classifier result, so nothing can be served from the cache before it.
+	if versionKnown {
+		key := cacheKey(classificationRequest.TenantID, classificationRequest.Text, modelVersion)
+		if result, ok := r.get(key); ok {
+			result.CacheHit = true
+			return result, nil
+		}
+	}
+
+	result, err := r.Classifier.Classify(ctx, classificationRequest)
+	if err != nil {
+		return result, err
+	}
+
+	if !versionKnown || result.ModelVersion != modelVersion {
+		r.setModelVersion(result.ModelVersion)
+	}
+
+	// A degraded result of an ensemble with a failed member is not cached.
+	for _, member := range result.Members {
+		if member.Error != "" {
+			return result, nil
+		}
+	}
+	r.set(cacheKey(classificationRequest.TenantID, classificationRequest.Text, result.ModelVersion), result)
+
+	return result, nil
+}
+
+// AppliesRules reports whether the wrapped classifier applies the detection rules itself.
+func (r *ClassificationCacheRepository) AppliesRules() bool {
+	return AppliesRules(r.Classifier)
+}
+
+// Health reports the health of the wrapped classifier. A classifier that does not report its health is considered healthy.
+func (r *ClassificationCacheRepository) Health() (bool, interface{}) {
+	reporter, ok := r.Classifier.(healthReporter)
+	if !ok {
+		return true, nil
+	}
+
+	return reporter.Health()
+}
+
+// cacheKey hashes the text with its whitespace normalized, since it does not affect the classifier, along with the tenant and the model version.
+func cacheKey(tenantID uint, text string, modelVersion string) string {
+	hash := sha256.Sum256([]byte(strconv.FormatUint(uint64(tenantID), 10) + "\x00" + modelVersion + "\x00" + strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(hash[:])
+}
+
+func (r *ClassificationCacheRepository) get(key string) (dto.ClassifierResult, bool) {
+	r.mu.Lock()
+	if element, ok := r.items[key]; ok {
+		item := element.Value.(*cacheItem)
+		if time.Now().Before(item.expiresAt) {
+			r.lru.MoveToFront(element)
+			r.mu.Unlock()
+			return item.result, true
+		}
+		r.lru.Remove(element)
+		delete(r.items, key)
+	}
+	r.mu.Unlock()
+
+	if !r.cfg.Persistent {
+		return dto.ClassifierResult{}, false
+	}
+
+	var entry models.ClassificationCacheEntry
+	err := r.DB.Where("key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.Error("Failed to retrieve cached classification result. ERR: ", err.Error())
+		}
+		return dto.ClassifierResult{}, false
+	}
+
+	result := dto.ClassifierResult{
+		Result:       entry.Result,
+		Score:        entry.Score,
+		Threshold:    entry.Threshold,
+		ModelVersion: entry.ModelVersion,
+		Members:      entry.MemberVerdicts,
+	}
+	r.setMemory(key, result, entry.ExpiresAt)
+
+	return result, true
+}
+
+func (r *ClassificationCacheRepository) set(key string, result dto.ClassifierResult) {
+	expiresAt := time.Now().Add(time.Duration(r.cfg.TTL) * time.Second)
+	r.setMemory(key, result, expiresAt)
+
+	if !r.cfg.Persistent {
+		return
+	}
+
+	err := r.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.ClassificationCacheEntry{
+		Key:            key,
+		ModelVersion:   result.ModelVersion,
+		Result:         result.Result,
+		Score:          result.Score,
+		Threshold:      result.Threshold,
+		MemberVerdicts: result.Members,
+		ExpiresAt:      expiresAt,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to insert classification result into the cache. ERR: ", err.Error())
+	}
+}
+
+// setMemory stores the result in the in-memory LRU and evicts the least recently used results above the size limit.
+func (r *ClassificationCacheRepository) setMemory(key string, result dto.ClassifierResult, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if element, ok := r.items[key]; ok {
+		element.Value = &cacheItem{key: key, result: result, expiresAt: expiresAt}
+		r.lru.MoveToFront(element)
+		return
+	}
+
+	r.items[key] = r.lru.PushFront(&cacheItem{key: key, result: result, expiresAt: expiresAt})
+	for r.cfg.Size > 0 && r.lru.Len() > r.cfg.Size {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.items, oldest.Value.(*cacheItem).key)
+	}
+}
+
+// setModelVersion switches the lookups to the results of the model version the classifier reported last
+// and purges the results of other versions from the memory and the database.
+func (r *ClassificationCacheRepository) setModelVersion(modelVersion string) {
+	r.mu.Lock()
+	previousVersion, versionKnown := r.modelVersion, r.versionKnown
+	r.modelVersion = modelVersion
+	r.versionKnown = true
+	if !versionKnown || previousVersion == modelVersion {
+		r.mu.Unlock()
+		return
+	}
+
+	for element := r.lru.Front(); element != nil; {
+		next := element.Next()
+		if item := element.Value.(*cacheItem); item.result.ModelVersion != modelVersion {
+			r.lru.Remove(element)
+			delete(r.items, item.key)
+		}
+		element = next
+	}
+	r.mu.Unlock()
+
+	r.logger.Info("Classifier model changed from ", previousVersion, " to ", modelVersion, ". Purged the cached results of other models.")
+
+	if !r.cfg.Persistent {
+		return
+	}
+
+	err := r.DB.Where("model_version <> ?", modelVersion).Delete(&models.ClassificationCacheEntry{}).Error
+	if err != nil {
+		r.logger.Error("Unable to purge results of other models from the classification cache. ERR: ", err.Error())
+	}
+}
+
+// startVersionChecks classifies a probe text with the wrapped classifier right away and then periodically, so a new model
+// is noticed before cached results of the previous model are served. A non-positive interval disables the checks.
+func (r *ClassificationCacheRepository) startVersionChecks(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			r.checkModelVersion(interval)
+			<-ticker.C
+		}
+	}()
+}
+
+// checkModelVersion learns the version of the current model from a probe classification. A failed probe keeps the known version.
+func (r *ClassificationCacheRepository) checkModelVersion(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := r.Classifier.Classify(ctx, dto.ClassificationRequest{Text: modelVersionProbeText})
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	changed := !r.versionKnown || r.modelVersion != result.ModelVersion
+	r.mu.Unlock()
+	if changed {
+		r.setModelVersion(result.ModelVersion)
+	}
+}
+
+// startPurge periodically deletes the expired results from the database.
+func (r *ClassificationCacheRepository) startPurge(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := r.DB.Where("expires_at <= ?", time.Now()).Delete(&models.ClassificationCacheEntry{}).Error
+			if err != nil {
+				r.logger.Error("Unable to purge expired results from the classification cache. ERR: ", err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// countingClassifier counts the texts it classified and returns the configured result.
+type countingClassifier struct {
+	calls  int
+	result dto.ClassifierResult
+}
+
+func (c *countingClassifier) Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	c.calls++
+	return c.result, nil
+}
+
+func TestClassificationCacheRepository(t *testing.T) {
+	type request struct {
+		tenantID     uint
+		text         string
+		modelVersion string // Version the classifier reports from this request on.
+		checkVersion bool   // Probes the version of the model before the request.
+		wantHit      bool
+	}
+
+	result := dto.ClassifierResult{Result: "Normal", Score: 0.25, Threshold: 0.5, ModelVersion: "v1"}
+	degradedResult := dto.ClassifierResult{
+		Result: "Normal", Score: 0.25, Threshold: 0.5, ModelVersion: "v1",
+		Members: models.MemberVerdicts{{Name: "heuristic", Score: 0.25}, {Name: "model", Error: "timeout"}},
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.CacheConfiguration
+		result     dto.ClassifierResult
+		requests   []request
+		wantCached int
+	}{
+		{
+			name:   "repeated text is served from the cache",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 10, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "hello world", wantHit: false},
+				{tenantID: 1, text: "hello world", wantHit: true},
+				{tenantID: 1, text: "  hello \n world ", wantHit: true},
+				{tenantID: 1, text: "hello", wantHit: false},
+			},
+			wantCached: 2,
+		},
+		{
+			name:   "tenants do not share results",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 10, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "hello world", wantHit: false},
+				{tenantID: 2, text: "hello world", wantHit: false},
+				{tenantID: 2, text: "hello world", wantHit: true},
+			},
+			wantCached: 2,
+		},
+		{
+			name:   "version probe purges results of the previous model",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 10, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "hello world", modelVersion: "v1", wantHit: false},
+				{tenantID: 1, text: "hello world", modelVersion: "v2", checkVersion: true, wantHit: false},
+				{tenantID: 1, text: "hello world", wantHit: true},
+			},
+			wantCached: 1,
+		},
+		{
+			name:   "uncached text revealing a new model purges results of the previous model",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 10, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "a", modelVersion: "v1", wantHit: false},
+				{tenantID: 1, text: "b", wantHit: false},
+				{tenantID: 1, text: "c", modelVersion: "v2", wantHit: false},
+				{tenantID: 1, text: "a", wantHit: false},
+				{tenantID: 1, text: "c", wantHit: true},
+			},
+			wantCached: 2,
+		},
+		{
+			name:   "least recently used results are evicted",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 2, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "a", wantHit: false},
+				{tenantID: 1, text: "b", wantHit: false},
+				{tenantID: 1, text: "a", wantHit: true},
+				{tenantID: 1, text: "c", wantHit: false},
+				{tenantID: 1, text: "a", wantHit: true},
+				{tenantID: 1, text: "b", wantHit: false},
+			},
+			wantCached: 2,
+		},
+		{
+			name:   "degraded ensemble results are not cached",
+			cfg:    config.CacheConfiguration{Enabled: true, Size: 10, TTL: 60},
+			result: degradedResult,
+			requests: []request{
+				{tenantID: 1, text: "hello world", wantHit: false},
+				{tenantID: 1, text: "hello world", wantHit: false},
+			},
+		},
+		{
+			name:   "disabled cache",
+			cfg:    config.CacheConfiguration{Enabled: false, Size: 10, TTL: 60},
+			result: result,
+			requests: []request{
+				{tenantID: 1, text: "hello world", wantHit: false},
+				{tenantID: 1, text: "hello world", wantHit: false},
+			},
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier := &countingClassifier{result: tt.result}
+			cache := NewClassificationCacheRepository(classifier, nil, tt.cfg, logger)
+
+			for i, request := range tt.requests {
+				if request.modelVersion != "" {
+					classifier.result.ModelVersion = request.modelVersion
+				}
+				if request.checkVersion {
+					cache.checkModelVersion(time.Second)
+				}
+
+				calls := classifier.calls
+				got, err := cache.Classify(context.Background(), dto.ClassificationRequest{TenantID: request.tenantID, Text: request.text})
+				if err != nil {
+					t.Fatalf("request %d: Classify() error = %v", i, err)
+				}
+				if hit := classifier.calls == calls; hit != request.wantHit || got.CacheHit != request.wantHit {
+					t.Errorf("request %d (%q): cache hit = %t (CacheHit %t), want %t", i, request.text, hit, got.CacheHit, request.wantHit)
+				}
+			}
+
+			if cached := cache.lru.Len(); cached != tt.wantCached {
+				t.Errorf("cached results = %d, want %d", cached, tt.wantCached)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/internal/models"
+)
+
+type ClassificationJobRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewClassificationJobRepository(db *gorm.DB, logger *logrus.Logger) *ClassificationJobRepository {
+	return &ClassificationJobRepository{DB: db, logger: logger}
+}
+
+func (r *ClassificationJobRepository) InsertJob(job *models.ClassificationJob) error {
+	if err := r.DB.Create(job).Error; err != nil {
+		r.logger.Error("Unable to insert classification job into the database. ERR: ", err.Error())
+		return errors.New("unable to insert object")
+	}
+
+	return nil
+}
+
+// SelectJobByID returns a job of the tenant.
+func (r *ClassificationJobRepository) SelectJobByID(tenantID uint, id string) (models.ClassificationJob, error) {
+	var job models.ClassificationJob
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("id = ?", id).First(&job).Error; err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
+
+// ClaimUnfinishedJobs returns up to limit jobs of all tenants that are pending or running, but not leased by a live worker
+// (e.g. jobs of a replica that stopped), and leases them to the instance until leaseUntil, so no other replica processes them meanwhile.
+func (r *ClassificationJobRepository) ClaimUnfinishedJobs(instanceID string, now time.Time, leaseUntil time.Time, limit int) ([]models.ClassificationJob, error) {
+	var jobs []models.ClassificationJob
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(allTenants).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ?", []string{models.JobStatusPending, models.JobStatusRunning}).
+			Where("lease_until IS NULL OR lease_until < ?", now).
+			Order("created_at asc").
+			Limit(limit).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return tx.Model(&models.ClassificationJob{}).Scopes(allTenants).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"locked_by":   instanceID,
+			"lease_until": leaseUntil,
+		}).Error
+	})
+	if err != nil {
+		r.logger.Error("Failed to claim unfinished classification jobs. ERR: ", err.Error())
+		return nil, errors.New("unable to claim jobs")
+	}
+
+	return jobs, nil
+}
+
+// RenewJobLeases extends the leases of the unfinished jobs of all tenants that are held by the instance until leaseUntil.
+func (r *ClassificationJobRepository) RenewJobLeases(instanceID string, leaseUntil time.Time) error {
+	err := r.DB.Model(&models.ClassificationJob{}).Scopes(allTenants).
+		Where("locked_by = ? AND status IN ?", instanceID, []string{models.JobStatusPending, models.JobStatusRunning}).
+		Update("lease_until", leaseUntil).Error
+	if err != nil {
+		r.logger.Error("Unable to renew classification job leases. ERR: ", err.Error())
+		return errors.New("unable to update jobs")
+	}
+
+	return nil
+}
+
+// StartJob marks a job of the tenant as running, unless its lease was taken over by another instance in the meantime.
+// It reports whether the instance still holds the job.
+func (r *ClassificationJobRepository) StartJob(tenantID uint, id string, instanceID string, leaseUntil time.Time) (bool, error) {
+	result := r.DB.Model(&models.ClassificationJob{}).Scopes(tenantScope(tenantID)).Where("id = ? AND locked_by = ?", id, instanceID).Updates(map[string]interface{}{
+		"status":      models.JobStatusRunning,
+		"lease_until": leaseUntil,
+	})
+	if result.Error != nil {
+		r.logger.Error("Unable to start classification job. ERR: ", result.Error.Error())
+		return false, errors.New("unable to update job")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateJobStatus sets the status of a job of the tenant along with its log ID and error, if any.
+func (r *ClassificationJobRepository) UpdateJobStatus(tenantID uint, id string, status string, logID *uint, errMessage string) error {
+	err := r.DB.Model(&models.ClassificationJob{}).Scopes(tenantScope(tenantID)).Where("id = ?", id).Updates(map[string]interface{}{
+		"status": status,
+		"log_id": logID,
+		"error":  errMessage,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update classification job status. ERR: ", err.Error())
+		return errors.New("unable to update job")
+	}
+
+	return nil
+}
+
+// FinishJob stores the outcome of a job of the tenant. If webhookDueAt is set, a webhook delivery is scheduled along with it,
+// so a finished job cannot lose its delivery.
+func (r *ClassificationJobRepository) FinishJob(tenantID uint, id string, status string, logID *uint, errMessage string, webhookDueAt *time.Time) error {
+	updates := map[string]interface{}{
+		"status": status,
+		"log_id": logID,
+		"error":  errMessage,
+	}
+	if webhookDueAt != nil {
+		updates["webhook_status"] = models.WebhookStatusPending
+		updates["webhook_attempts"] = 0
+		updates["webhook_next_attempt_at"] = webhookDueAt
+	}
+
+	if err := r.DB.Model(&models.ClassificationJob{}).Scopes(tenantScope(tenantID)).Where("id = ?", id).Updates(updates).Error; err != nil {
+		r.logger.Error("Unable to finish classification job. ERR: ", err.Error())
+		return errors.New("unable to update job")
+	}
+
+	return nil
+}
+
+// ClaimDueWebhookDeliveries returns up to limit jobs of all tenants whose pending webhook delivery is due and postpones their
+// next attempt until leaseUntil, so no other worker or replica delivers them meanwhile. A delivery whose worker stopped
+// before recording the outcome is due again once the lease has expired.
+func (r *ClassificationJobRepository) ClaimDueWebhookDeliveries(now time.Time, leaseUntil time.Time, limit int) ([]models.ClassificationJob, error) {
+	var jobs []models.ClassificationJob
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Scopes(allTenants).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("webhook_status = ? AND webhook_next_attempt_at <= ?", models.WebhookStatusPending, now).
+			Order("webhook_next_attempt_at asc").
+			Limit(limit).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return tx.Model(&models.ClassificationJob{}).Scopes(allTenants).Where("id IN ?", ids).Update("webhook_next_attempt_at", leaseUntil).Error
+	})
+	if err != nil {
+		r.logger.Error("Failed to claim due webhook deliveries. ERR: ", err.Error())
+		return nil, errors.New("unable to claim webhook deliveries")
+	}
+
+	return jobs, nil
+}
+
+// UpdateWebhookStatus records the outcome of a webhook delivery attempt of a job of the tenant. nextAttemptAt schedules the next attempt of a pending delivery.
+func (r *ClassificationJobRepository) UpdateWebhookStatus(tenantID uint, id string, status string, attempts int, nextAttemptAt *time.Time) error {
+	err := r.DB.Model(&models.ClassificationJob{}).Scopes(tenantScope(tenantID)).Where("id = ?", id).Updates(map[string]interface{}{
+		"webhook_status":          status,
+		"webhook_attempts":        attempts,
+		"webhook_next_attempt_at": nextAttemptAt,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update classification job webhook status. ERR: ", err.Error())
+		return errors.New("unable to update job")
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/models"
 )
 
@@ -15,30 +20,332 @@ func NewClassificationLogsRepository(db *gorm.DB, logger *logrus.Logger) *Classi
 	return &ClassificationLogsRepository{DB: db, logger: logger}
 }
 
-// InsertClassificationRequest inserts a classification  log (ClassificationLog) into the database.
-func (r *ClassificationLogsRepository) InsertClassificationLog(classificationLog models.ClassificationLog) error {
-	return r.DB.Create(&classificationLog).Error
+// InsertClassificationLog inserts a classification log (ClassificationLog) into the database and sets its ID.
+func (r *ClassificationLogsRepository) InsertClassificationLog(classificationLog *models.ClassificationLog) error {
+	return r.DB.Create(classificationLog).Error
 }
 
 // SelectClassificationLogByID returns a single database entry for a classification log based on ID.
-func (r *ClassificationLogsRepository) SelectClassificationLogByID(id uint) (models.ClassificationLog, error) {
+// Logs outside of the scope are not found.
+func (r *ClassificationLogsRepository) SelectClassificationLogByID(id uint, scope dto.ClassificationLogScope) (models.ClassificationLog, error) {
 	var classificationLog models.ClassificationLog
-	if err := r.DB.First(&classificationLog, id).Error; err != nil {
+	if err := r.scopeQuery(scope).First(&classificationLog, id).Error; err != nil {
 		return classificationLog, err
 	}
 
 	return classificationLog, nil
 }
 
-// SelectClassificationLogsByPage retrieves database entries of classification logs, based on page and limit for offsetting.
-func (r *ClassificationLogsRepository) SelectClassificationLogsByPage(page int, limit int, orderBy string) ([]models.ClassificationLog, error) {
+// SelectClassificationLogsByPage retrieves database entries of classification logs matching the filter, based on page and limit for offsetting.
+func (r *ClassificationLogsRepository) SelectClassificationLogsByPage(page int, limit int, orderBy string, filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope) ([]models.ClassificationLog, error) {
+	var classificationLogs []models.ClassificationLog
+
+	query, err := r.filterQuery(filter, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Essentialy, `SELECT * FROM classification_logs WHERE {filter} ORDER BY {orderBy} LIMIT {limit} OFFSET {(page-1)*limit};`.
+	if err := query.Offset((page - 1) * limit).Limit(limit).Order(orderBy).Find(&classificationLogs).Error; err != nil {
+		r.logger.Error("Failed to retrieve classification log from database. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve classification logs")
+	}
+
+	return classificationLogs, nil
+}
+
+// CountClassificationLogs returns the number of classification logs matching the filter.
+func (r *ClassificationLogsRepository) CountClassificationLogs(filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope) (int64, error) {
+	var total int64
+
+	query, err := r.filterQuery(filter, scope)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count classification logs. ERR: ", err.Error())
+		return 0, errors.New("unable to retrieve classification logs")
+	}
+
+	return total, nil
+}
+
+// SelectClassificationLogsByKeyset retrieves up to limit classification logs matching the filter that follow the given position
+// in the order of their creation time and ID, or the first logs if there is no position.
+func (r *ClassificationLogsRepository) SelectClassificationLogsByKeyset(after *dto.ClassificationLogCursor, ascending bool, limit int, filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope) ([]models.ClassificationLog, error) {
+	var classificationLogs []models.ClassificationLog
+
+	query, err := r.filterQuery(filter, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "desc", "<"
+	if ascending {
+		direction, comparison = "asc", ">"
+	}
+
+	// The row comparison can use the index on (created_at, id), unlike an offset that scans all skipped rows.
+	if after != nil {
+		query = query.Where("(created_at, id) "+comparison+" (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	err = query.Order("created_at " + direction + ", id " + direction).Limit(limit).Find(&classificationLogs).Error
+	if err != nil {
+		r.logger.Error("Failed to retrieve classification logs from database. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve classification logs")
+	}
+
+	return classificationLogs, nil
+}
+
+// StreamClassificationLogs passes every classification log matching the filter to handle, in the order of their creation.
+// The logs are read from the database cursor one at a time instead of being loaded into memory. Streaming stops at the first error of handle.
+func (r *ClassificationLogsRepository) StreamClassificationLogs(ctx context.Context, filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope, handle func(classificationLog models.ClassificationLog) error) error {
+	query, err := r.filterQuery(filter, scope)
+	if err != nil {
+		return err
+	}
+
+	rows, err := query.WithContext(ctx).Order("created_at asc, id asc").Rows()
+	if err != nil {
+		r.logger.Error("Failed to retrieve classification logs from database. ERR: ", err.Error())
+		return errors.New("unable to retrieve classification logs")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var classificationLog models.ClassificationLog
+		if err := r.DB.ScanRows(rows, &classificationLog); err != nil {
+			r.logger.Error("Failed to read classification log from database. ERR: ", err.Error())
+			return errors.New("unable to retrieve classification logs")
+		}
+
+		if err := handle(classificationLog); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Failed to retrieve classification logs from database. ERR: ", err.Error())
+		return errors.New("unable to retrieve classification logs")
+	}
+
+	return nil
+}
+
+// scopeQuery starts a classification logs query that is restricted to the logs within the scope.
+// Every query that reads or changes logs on behalf of a caller must start from it.
+func (r *ClassificationLogsRepository) scopeQuery(scope dto.ClassificationLogScope) *gorm.DB {
+	query := r.DB.Model(&models.ClassificationLog{}).Scopes(tenantScope(scope.TenantID))
+	if scope.All {
+		return query
+	}
+	if scope.SourceName == "" {
+		// An unset scope grants access to nothing rather than everything.
+		return query.Where("FALSE")
+	}
+
+	return query.Where("source_name = ?", scope.SourceName)
+}
+
+// filterQuery narrows down a classification logs query to the logs within the scope that match the filter.
+func (r *ClassificationLogsRepository) filterQuery(filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope) (*gorm.DB, error) {
+	query := r.scopeQuery(scope)
+
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if filter.SourceName != "" {
+		query = query.Where("source_name = ?", filter.SourceName)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.Search != "" {
+		// The generated search_vector column is backed by a GIN index. websearch_to_tsquery accepts user input
+		// such as quoted phrases, "or" and "-" for excluded words without raising syntax errors.
+		query = query.Where("search_vector @@ websearch_to_tsquery('english', ?)", filter.Search)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.ConversationID != "" {
+		query = query.Where("conversation_id = ?", filter.ConversationID)
+	}
+	if filter.SourceRef != "" {
+		query = query.Where("source_ref = ?", filter.SourceRef)
+	}
+	if len(filter.Tags) > 0 {
+		// The containment operator can use the GIN index on the tags column.
+		tags, err := models.StringList(filter.Tags).Value()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("tags @> ?::jsonb", tags)
+	}
+
+	return query, nil
+}
+
+// SelectClassificationLogsByConversation returns the logs within the scope of a conversation of a source in chronological order, up to limit logs.
+func (r *ClassificationLogsRepository) SelectClassificationLogsByConversation(scope dto.ClassificationLogScope, sourceName string, conversationID string, limit int) ([]models.ClassificationLog, error) {
+	var classificationLogs []models.ClassificationLog
+
+	err := r.scopeQuery(scope).Where("source_name = ? AND conversation_id = ?", sourceName, conversationID).
+		Order("created_at asc, id asc").
+		Limit(limit).
+		Find(&classificationLogs).Error
+	if err != nil {
+		r.logger.Error("Failed to retrieve classification logs of conversation. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve conversation")
+	}
+
+	return classificationLogs, nil
+}
+
+// UpdateShadowResult stores the verdict of the shadow classifier next to the primary result of a classification log of the tenant.
+func (r *ClassificationLogsRepository) UpdateShadowResult(tenantID uint, id uint, result string, score float64, modelVersion string) error {
+	err := r.DB.Model(&models.ClassificationLog{}).Scopes(tenantScope(tenantID)).Where("id = ?", id).Updates(map[string]interface{}{
+		"shadow_result":        result,
+		"shadow_score":         score,
+		"shadow_model_version": modelVersion,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update shadow result of classification log. ERR: ", err.Error())
+		return errors.New("unable to update classification log")
+	}
+
+	return nil
+}
+
+// SelectShadowReport counts the agreements and disagreements between the primary and shadow verdicts of the logs within the scope
+// per shadow model version. A zero from or to leaves the date range open on that side.
+func (r *ClassificationLogsRepository) SelectShadowReport(scope dto.ClassificationLogScope, from time.Time, to time.Time) ([]dto.ShadowModelReport, error) {
+	var reports []dto.ShadowModelReport
+
+	err := r.shadowQuery(scope, from, to).
+		Select(`shadow_model_version,
+			COUNT(*) AS compared,
+			COUNT(*) FILTER (WHERE result <> shadow_result) AS disagreements,
+			COUNT(*) FILTER (WHERE result = 'Injection' AND shadow_result = 'Normal') AS missed_injections,
+			COUNT(*) FILTER (WHERE result = 'Normal' AND shadow_result = 'Injection') AS new_injections`).
+		Group("shadow_model_version").
+		Order("shadow_model_version").
+		Scan(&reports).Error
+	if err != nil {
+		r.logger.Error("Failed to retrieve shadow classification report. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve shadow report")
+	}
+
+	return reports, nil
+}
+
+// SelectShadowDisagreements returns the latest classification logs within the scope whose primary and shadow verdicts differ.
+func (r *ClassificationLogsRepository) SelectShadowDisagreements(scope dto.ClassificationLogScope, from time.Time, to time.Time, limit int) ([]models.ClassificationLog, error) {
+	var classificationLogs []models.ClassificationLog
+
+	err := r.shadowQuery(scope, from, to).Where("result <> shadow_result").Order("id desc").Limit(limit).Find(&classificationLogs).Error
+	if err != nil {
+		r.logger.Error("Failed to retrieve shadow classification disagreements. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve shadow disagreements")
+	}
+
+	return classificationLogs, nil
+}
+
+func (r *ClassificationLogsRepository) shadowQuery(scope dto.ClassificationLogScope, from time.Time, to time.Time) *gorm.DB {
+	query := r.scopeQuery(scope).Where("shadow_result IS NOT NULL")
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	return query
+}
+
+// UpdateLabel stores the ground truth label of a classification log along with its reviewer. It returns false if there is no such log within the scope.
+func (r *ClassificationLogsRepository) UpdateLabel(id uint, scope dto.ClassificationLogScope, label string, reviewer string, note string) (bool, error) {
+	result := r.scopeQuery(scope).Where("id = ?", id).Updates(map[string]interface{}{
+		"label":       label,
+		"reviewer":    reviewer,
+		"review_note": note,
+		"reviewed_at": time.Now(),
+	})
+	if result.Error != nil {
+		r.logger.Error("Unable to update label of classification log. ERR: ", result.Error.Error())
+		return false, errors.New("unable to update label")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// failModeVersions are the model versions of the verdicts returned by the fail mode while the classifier was unavailable.
+var failModeVersions = []string{models.ModelVersionFailOpen, models.ModelVersionFailClosed}
+
+// SelectReviewQueue returns unlabeled classification logs matching the filter, starting with the least confident verdicts,
+// i.e. those whose score is closest to their threshold. A positive maxMargin leaves out logs whose score is further from the threshold.
+// Verdicts of the fail mode are left out, since their scores are not the classifier's. The order matches classification_logs_review_queue_idx.
+func (r *ClassificationLogsRepository) SelectReviewQueue(filter dto.ClassificationLogFilter, scope dto.ClassificationLogScope, maxMargin float64, limit int) ([]models.ClassificationLog, error) {
 	var classificationLogs []models.ClassificationLog
 
-	// Essentialy, `SELECT * FROM classification_logs ORDER BY id {desc || asc} LIMIT {limit} OFFSET {(page-1)*limit};`.
-	if err := r.DB.Offset((page - 1) * limit).Limit(limit).Order(orderBy).Find(&classificationLogs).Error; err != nil {
-		r.logger.Error("Failed to retrieve classification log from database.")
+	query, err := r.filterQuery(filter, scope)
+	if err != nil {
 		return nil, err
 	}
 
+	query = query.Where("label IS NULL").Where("model_version IS NULL OR model_version NOT IN ?", failModeVersions)
+	if maxMargin > 0 {
+		query = query.Where("ABS(score - threshold) <= ?", maxMargin)
+	}
+
+	err = query.Order("ABS(score - threshold) asc, id desc").Limit(limit).Find(&classificationLogs).Error
+	if err != nil {
+		r.logger.Error("Failed to retrieve review queue. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve review queue")
+	}
+
 	return classificationLogs, nil
 }
+
+// SelectLabelMetrics counts the verdicts of the labeled classification logs within the scope created in the date range against their labels,
+// grouped by the given column ("source_name" or "model_version"), or over all logs if the column is empty.
+// Verdicts of the fail mode are not counted, since the classifier did not make them.
+func (r *ClassificationLogsRepository) SelectLabelMetrics(scope dto.ClassificationLogScope, groupBy string, from time.Time, to time.Time) ([]dto.LabelMetrics, error) {
+	var metrics []dto.LabelMetrics
+
+	query := r.scopeQuery(scope).Where("label IS NOT NULL").Where("model_version IS NULL OR model_version NOT IN ?", failModeVersions)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+
+	columns := `COUNT(*) AS labeled,
+		COUNT(*) FILTER (WHERE result = 'Injection' AND label = 'injection') AS true_positives,
+		COUNT(*) FILTER (WHERE result = 'Injection' AND label = 'benign') AS false_positives,
+		COUNT(*) FILTER (WHERE result <> 'Injection' AND label = 'benign') AS true_negatives,
+		COUNT(*) FILTER (WHERE result <> 'Injection' AND label = 'injection') AS false_negatives`
+	switch groupBy {
+	case "":
+		query = query.Select(columns)
+	case "source_name", "model_version":
+		query = query.Select(groupBy + ", " + columns).Group(groupBy).Order(groupBy)
+	default:
+		return nil, errors.New("unknown label metrics grouping")
+	}
+
+	if err := query.Scan(&metrics).Error; err != nil {
+		r.logger.Error("Failed to retrieve label metrics. ERR: ", err.Error())
+		return nil, errors.New("unable to retrieve label metrics")
+	}
+
+	return metrics, nil
+}
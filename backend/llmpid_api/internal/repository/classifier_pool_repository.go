@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+)
+
+// Balancing strategies of the classifier pool.
+const (
+	BalancingLeastOutstanding = "least_outstanding"
+	BalancingWeighted         = "weighted"
+)
+
+// ErrNoHealthyBackend is returned when every replica of the classifier service is ejected or has an open circuit breaker.
+var ErrNoHealthyBackend = fmt.Errorf("no healthy classifier backend: %w", ErrCircuitOpen)
+
+type classifierBackend struct {
+	url       string
+	healthURL string
+	weight    int
+	client    *InternalClassifierAPIRepository
+	breaker   *CircuitBreaker
+
+	outstanding atomic.Int64
+
+	// Guarded by the mutex of the pool.
+	currentWeight int
+	healthy       bool
+	failedProbes  int
+	lastError     string
+}
+
+// ClassifierPoolRepository spreads classification requests across several replicas of the internal classifier service.
+// Replicas are probed on their health route and ejected after repeated failed probes until they recover.
+type ClassifierPoolRepository struct {
+	mu                sync.Mutex
+	backends          []*classifierBackend
+	balancing         string
+	ejectionThreshold int
+	probeClient       *http.Client
+	logger            *logrus.Logger
+}
+
+// NewClassifierPoolRepository creates a pool of the configured endpoints, or of ClassifierAPIPath if there are none,
+// and starts probing their health.
+func NewClassifierPoolRepository(cfg config.ClassifierConfiguration, logger *logrus.Logger) (*ClassifierPoolRepository, error) {
+	endpoints := cfg.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []config.ClassifierEndpointConfiguration{{URL: cfg.ClassifierAPIPath}}
+	}
+
+	switch cfg.Balancing {
+	case "", BalancingLeastOutstanding, BalancingWeighted:
+	default:
+		return nil, fmt.Errorf("unknown classifier balancing strategy: %s", cfg.Balancing)
+	}
+
+	requestTimeout := time.Duration(cfg.RequestTimeout) * time.Millisecond
+	pool := &ClassifierPoolRepository{
+		balancing:         cfg.Balancing,
+		ejectionThreshold: max(cfg.EjectionThreshold, 1),
+		probeClient:       &http.Client{Timeout: requestTimeout},
+		logger:            logger,
+	}
+
+	for _, endpoint := range endpoints {
+		healthURL := endpoint.HealthURL
+		if healthURL == "" {
+			parsedURL, err := url.Parse(endpoint.URL)
+			if err != nil || parsedURL.Host == "" {
+				return nil, fmt.Errorf("invalid classifier endpoint: %s", endpoint.URL)
+			}
+			healthURL = parsedURL.Scheme + "://" + parsedURL.Host + "/health"
+		}
+
+		breaker := NewCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second)
+		pool.backends = append(pool.backends, &classifierBackend{
+			url:       endpoint.URL,
+			healthURL: healthURL,
+			weight:    max(endpoint.Weight, 1),
+			client:    NewInternalClassifierAPIRepository(endpoint.URL, requestTimeout, cfg.MaxRetries, breaker, logger),
+			breaker:   breaker,
+			healthy:   true,
+		})
+	}
+
+	pool.startHealthChecks(time.Duration(cfg.HealthCheckInterval) * time.Second)
+
+	return pool, nil
+}
+
+// Classify sends the text to one of the available replicas. If the replica fails, the request fails over to the next one.
+func (r *ClassifierPoolRepository) Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	tried := make(map[*classifierBackend]bool, len(r.backends))
+	lastErr := ErrNoHealthyBackend
+
+	for {
+		backend := r.pick(tried)
+		if backend == nil {
+			return dto.ClassifierResult{}, lastErr
+		}
+		tried[backend] = true
+
+		backend.outstanding.Add(1)
+		classifierResult, err := backend.client.Classify(ctx, classificationRequest)
+		backend.outstanding.Add(-1)
+
+		if err == nil || ctx.Err() != nil || !(errors.Is(err, ErrCircuitOpen) || errors.Is(err, errClassifierServer)) {
+			return classifierResult, err
+		}
+		lastErr = err
+	}
+}
+
+// pick selects the replica for the next request among the healthy replicas that were not tried yet.
+func (r *ClassifierPoolRepository) pick(tried map[*classifierBackend]bool) *classifierBackend {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var candidates []*classifierBackend
+	for _, backend := range r.backends {
+		if backend.healthy && !tried[backend] && backend.breaker.Available() {
+			candidates = append(candidates, backend)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if r.balancing == BalancingWeighted {
+		// Smooth weighted round-robin, which interleaves the replicas instead of sending bursts to the heaviest one.
+		var selected *classifierBackend
+		totalWeight := 0
+		for _, backend := range candidates {
+			backend.currentWeight += backend.weight
+			totalWeight += backend.weight
+			if selected == nil || backend.currentWeight > selected.currentWeight {
+				selected = backend
+			}
+		}
+		selected.currentWeight -= totalWeight
+		return selected
+	}
+
+	// Least outstanding requests relative to the weight of the replica.
+	selected := candidates[0]
+	for _, backend := range candidates[1:] {
+		if backend.outstanding.Load()*int64(selected.weight) < selected.outstanding.Load()*int64(backend.weight) {
+			selected = backend
+		}
+	}
+	return selected
+}
+
+// startHealthChecks probes the health route of every replica in the given interval. A non-positive interval disables probing.
+func (r *ClassifierPoolRepository) startHealthChecks(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for _, backend := range r.backends {
+				r.probe(backend)
+			}
+		}
+	}()
+}
+
+// probe checks the health of a replica. The replica is ejected after EjectionThreshold consecutive failed probes
+// and receives requests again after its first successful probe.
+func (r *ClassifierPoolRepository) probe(backend *classifierBackend) {
+	var probeErr error
+	resp, err := r.probeClient.Get(backend.healthURL)
+	if err != nil {
+		probeErr = err
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			probeErr = errors.New("health check returned " + resp.Status)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if probeErr == nil {
+		if !backend.healthy {
+			r.logger.Info("Classifier backend recovered and was re-admitted to the pool: ", backend.url)
+		}
+		backend.healthy = true
+		backend.failedProbes = 0
+		backend.lastError = ""
+		return
+	}
+
+	backend.failedProbes++
+	backend.lastError = probeErr.Error()
+	if backend.healthy && backend.failedProbes >= r.ejectionThreshold {
+		backend.healthy = false
+		r.logger.Warn("Classifier backend was ejected from the pool: ", backend.url, ". ERR: ", probeErr)
+	}
+}
+
+// Health reports the status of every replica. The pool is healthy as long as one replica can receive requests.
+func (r *ClassifierPoolRepository) Health() (bool, interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	healthy := false
+	backends := make([]dto.ClassifierBackendHealth, len(r.backends))
+	for i, backend := range r.backends {
+		backends[i] = dto.ClassifierBackendHealth{
+			URL:         backend.url,
+			Weight:      backend.weight,
+			Healthy:     backend.healthy,
+			Breaker:     backend.breaker.State(),
+			Outstanding: backend.outstanding.Load(),
+			LastError:   backend.lastError,
+		}
+		if backend.healthy && backend.breaker.Available() {
+			healthy = true
+		}
+	}
+
+	return healthy, backends
+}
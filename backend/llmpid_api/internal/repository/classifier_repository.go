@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+)
+
+// Classifier is implemented by every prompt injection classification engine.
+type Classifier interface {
+	Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error)
+}
+
+// RuleApplier is implemented by classifiers that may apply the detection rules themselves, i.e. ensembles with a "rules" member.
+type RuleApplier interface {
+	AppliesRules() bool
+}
+
+// healthReporter is implemented by classifiers that report the health of their backends, i.e. the http engine and ensembles.
+type healthReporter interface {
+	Health() (bool, interface{})
+}
+
+// ReportsHealth reports whether the classifier reports the health of its backends, looking through the cache wrapping it.
+func ReportsHealth(classifier Classifier) bool {
+	if cache, ok := classifier.(*ClassificationCacheRepository); ok {
+		classifier = cache.Classifier
+	}
+	_, ok := classifier.(healthReporter)
+	return ok
+}
+
+// AppliesRules reports whether the classifier already weighs the detection rules in its results, so they must not be applied again.
+func AppliesRules(classifier Classifier) bool {
+	ruleApplier, ok := classifier.(RuleApplier)
+	return ok && ruleApplier.AppliesRules()
+}
+
+// NewClassifier instantiates the classification engine selected by the classifier configuration.
+// Supported engines are "http" (default), "heuristic", "stub" and "ensemble". The detection rules are used by ensembles with a "rules" member.
+func NewClassifier(cfg config.ClassifierConfiguration, ruleRepo *RuleRepository, logger *logrus.Logger) (Classifier, error) {
+	switch cfg.Engine {
+	case "", "http":
+		return NewClassifierPoolRepository(cfg, logger)
+	case "heuristic":
+		return NewHeuristicClassifierRepository(logger), nil
+	case "stub":
+		return NewStubClassifierRepository(cfg.StubResult, logger), nil
+	case "ensemble":
+		return NewEnsembleClassifierRepository(cfg, ruleRepo, logger)
+	default:
+		return nil, fmt.Errorf("unknown classifier engine: %s", cfg.Engine)
+	}
+}
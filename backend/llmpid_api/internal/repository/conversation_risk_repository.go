@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/internal/models"
+)
+
+type ConversationRiskRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewConversationRiskRepository(db *gorm.DB, logger *logrus.Logger) *ConversationRiskRepository {
+	return &ConversationRiskRepository{DB: db, logger: logger}
+}
+
+// UpdateConversationRisk applies the update to the risk state of a conversation, creating the state on its first turn.
+// The state is locked for the duration of the update, so concurrent turns of the same conversation are applied one after another.
+func (r *ConversationRiskRepository) UpdateConversationRisk(tenantID uint, sourceName string, conversationID string, update func(risk *models.ConversationRisk)) (models.ConversationRisk, error) {
+	var risk models.ConversationRisk
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ConversationRisk{
+			TenantID:       tenantID,
+			SourceName:     sourceName,
+			ConversationID: conversationID,
+			Action:         models.ActionAllow,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Scopes(tenantScope(tenantID)).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("source_name = ? AND conversation_id = ?", sourceName, conversationID).
+			First(&risk).Error
+		if err != nil {
+			return err
+		}
+
+		update(&risk)
+
+		return tx.Scopes(tenantScope(tenantID)).Save(&risk).Error
+	})
+	if err != nil {
+		r.logger.Error("Unable to update conversation risk. ERR: ", err.Error())
+		return risk, errors.New("unable to update conversation risk")
+	}
+
+	return risk, nil
+}
+
+// SelectConversationRisk returns the risk state of a conversation of the tenant, or nil if the conversation is unknown.
+func (r *ConversationRiskRepository) SelectConversationRisk(tenantID uint, sourceName string, conversationID string) (*models.ConversationRisk, error) {
+	var risk models.ConversationRisk
+
+	err := r.DB.Scopes(tenantScope(tenantID)).Where("source_name = ? AND conversation_id = ?", sourceName, conversationID).First(&risk).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve conversation risk. ERR: ", err.Error())
+		return nil, err
+	}
+
+	return &risk, nil
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// Voting strategies of the ensemble classifier.
+const (
+	EnsembleWeighted = "weighted"
+	EnsembleAny      = "any"
+	EnsembleMajority = "majority"
+)
+
+type ensembleMember struct {
+	name       string
+	weight     float64
+	classifier Classifier
+}
+
+// EnsembleClassifierRepository classifies texts with several detectors concurrently and combines their verdicts by voting.
+// The verdict of every member is returned along with the combined result.
+type EnsembleClassifierRepository struct {
+	members      []ensembleMember
+	strategy     string
+	appliesRules bool // Set if a member is backed by the detection rules.
+	logger       *logrus.Logger
+
+	mu            sync.Mutex
+	modelVersions []string // The last model version reported by every member.
+}
+
+func NewEnsembleClassifierRepository(cfg config.ClassifierConfiguration, ruleRepo *RuleRepository, logger *logrus.Logger) (*EnsembleClassifierRepository, error) {
+	switch cfg.Ensemble.Strategy {
+	case "", EnsembleWeighted, EnsembleAny, EnsembleMajority:
+	default:
+		return nil, fmt.Errorf("unknown ensemble strategy: %s", cfg.Ensemble.Strategy)
+	}
+	if len(cfg.Ensemble.Members) == 0 {
+		return nil, errors.New("ensemble does not contain any members")
+	}
+
+	ensemble := &EnsembleClassifierRepository{
+		strategy:      cfg.Ensemble.Strategy,
+		logger:        logger,
+		modelVersions: make([]string, len(cfg.Ensemble.Members)),
+	}
+	names := make(map[string]bool, len(cfg.Ensemble.Members))
+
+	for i, memberCfg := range cfg.Ensemble.Members {
+		if memberCfg.Name == "" {
+			memberCfg.Name = fmt.Sprintf("%s-%d", memberCfg.Engine, i+1)
+		}
+		if names[memberCfg.Name] {
+			return nil, fmt.Errorf("duplicate ensemble member name: %s", memberCfg.Name)
+		}
+		names[memberCfg.Name] = true
+
+		if memberCfg.Weight < 0 {
+			return nil, fmt.Errorf("ensemble member %s: weight must not be negative", memberCfg.Name)
+		}
+		if memberCfg.Weight == 0 {
+			memberCfg.Weight = 1
+		}
+
+		var classifier Classifier
+		switch memberCfg.Engine {
+		case "rules":
+			classifier = NewRuleClassifierRepository(ruleRepo)
+			ensemble.appliesRules = true
+		case "ensemble":
+			return nil, fmt.Errorf("ensemble member %s: ensembles cannot be nested", memberCfg.Name)
+		default:
+			engineCfg := cfg
+			engineCfg.Engine = memberCfg.Engine
+			engineCfg.ClassifierAPIPath = memberCfg.ClassifierAPIPath
+			engineCfg.Endpoints = memberCfg.Endpoints
+			engineCfg.StubResult = memberCfg.StubResult
+
+			var err error
+			classifier, err = NewClassifier(engineCfg, ruleRepo, logger)
+			if err != nil {
+				return nil, fmt.Errorf("ensemble member %s: %w", memberCfg.Name, err)
+			}
+		}
+
+		ensemble.members = append(ensemble.members, ensembleMember{name: memberCfg.Name, weight: memberCfg.Weight, classifier: classifier})
+	}
+
+	return ensemble, nil
+}
+
+// Classify sends the text to every member and combines the verdicts of the members that succeeded.
+// It fails only if every member failed.
+func (r *EnsembleClassifierRepository) Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	verdicts := make(models.MemberVerdicts, len(r.members))
+	errs := make([]error, len(r.members))
+	var wg sync.WaitGroup
+
+	for i, member := range r.members {
+		wg.Add(1)
+		go func(i int, member ensembleMember) {
+			defer wg.Done()
+
+			result, err := member.classifier.Classify(ctx, classificationRequest)
+			verdicts[i] = models.MemberVerdict{Name: member.name, Weight: member.weight}
+			if err != nil {
+				verdicts[i].Error = err.Error()
+				errs[i] = err
+				return
+			}
+
+			verdicts[i].Result = result.Result
+			verdicts[i].Score = result.Score
+			verdicts[i].Threshold = result.Threshold
+			verdicts[i].ModelVersion = result.ModelVersion
+		}(i, member)
+	}
+	wg.Wait()
+
+	var voters models.MemberVerdicts
+	for i, verdict := range verdicts {
+		if errs[i] == nil {
+			voters = append(voters, verdict)
+		}
+	}
+	if len(voters) == 0 {
+		return dto.ClassifierResult{}, errors.Join(errs...)
+	}
+
+	result := r.vote(voters)
+	result.Members = verdicts
+	result.ModelVersion = r.version(verdicts)
+
+	for i, err := range errs {
+		if err != nil {
+			r.logger.Warn("Ensemble member ", r.members[i].name, " failed and was left out of the vote. ERR: ", err)
+		}
+	}
+
+	return result, nil
+}
+
+// AppliesRules reports whether a member of the ensemble is backed by the detection rules.
+func (r *EnsembleClassifierRepository) AppliesRules() bool {
+	return r.appliesRules
+}
+
+// vote combines the verdicts of the members with the configured strategy.
+func (r *EnsembleClassifierRepository) vote(voters models.MemberVerdicts) dto.ClassifierResult {
+	result := dto.ClassifierResult{Result: "Normal"}
+
+	switch r.strategy {
+	case EnsembleAny:
+		// Any injection verdict wins. The score is the highest score of the members.
+		result.Threshold = 1
+		for _, voter := range voters {
+			result.Score = max(result.Score, voter.Score)
+			result.Threshold = min(result.Threshold, voter.Threshold)
+			if voter.Result == "Injection" {
+				result.Result = "Injection"
+			}
+		}
+	case EnsembleMajority:
+		// Most members must agree on an injection. The score is the average score of the members.
+		injections := 0
+		for _, voter := range voters {
+			result.Score += voter.Score / float64(len(voters))
+			result.Threshold += voter.Threshold / float64(len(voters))
+			if voter.Result == "Injection" {
+				injections++
+			}
+		}
+		if injections*2 > len(voters) {
+			result.Result = "Injection"
+		}
+	default:
+		// The weighted average score is compared with the weighted average threshold.
+		var totalWeight float64
+		for _, voter := range voters {
+			totalWeight += voter.Weight
+		}
+		for _, voter := range voters {
+			result.Score += voter.Score * voter.Weight / totalWeight
+			result.Threshold += voter.Threshold * voter.Weight / totalWeight
+		}
+		if result.Score >= result.Threshold {
+			result.Result = "Injection"
+		}
+	}
+
+	return result
+}
+
+// version identifies the ensemble by its strategy and the models of its members, so cached results are dropped once any member changes.
+// Members that failed are identified by the last model version they reported.
+func (r *EnsembleClassifierRepository) version(verdicts models.MemberVerdicts) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hash := sha256.New()
+	fmt.Fprintln(hash, r.strategy)
+	for i, verdict := range verdicts {
+		if verdict.Error == "" {
+			r.modelVersions[i] = verdict.ModelVersion
+		}
+		fmt.Fprintln(hash, verdict.Name, verdict.Weight, r.modelVersions[i])
+	}
+
+	return "ensemble-" + hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Health reports the status of the members that report their own health. The ensemble is healthy as long as one member is.
+func (r *EnsembleClassifierRepository) Health() (bool, interface{}) {
+	healthy := false
+	details := make(map[string]interface{}, len(r.members))
+
+	for _, member := range r.members {
+		reporter, ok := member.classifier.(healthReporter)
+		if !ok {
+			healthy = true
+			continue
+		}
+
+		memberHealthy, memberDetails := reporter.Health()
+		healthy = healthy || memberHealthy
+		details[member.name] = memberDetails
+	}
+
+	return healthy, details
+}
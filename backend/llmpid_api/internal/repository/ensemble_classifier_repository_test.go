@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"math"
+	"testing"
+
+	"llm-promp-inj.api/internal/models"
+)
+
+func TestEnsembleVote(t *testing.T) {
+	injectionHeavy := models.MemberVerdicts{
+		{Name: "a", Result: "Injection", Score: 0.75, Threshold: 0.5, Weight: 3},
+		{Name: "b", Result: "Normal", Score: 0.25, Threshold: 0.5, Weight: 1},
+	}
+	normalHeavy := models.MemberVerdicts{
+		{Name: "a", Result: "Injection", Score: 0.75, Threshold: 0.5, Weight: 1},
+		{Name: "b", Result: "Normal", Score: 0.25, Threshold: 0.5, Weight: 3},
+	}
+	twoOfThree := models.MemberVerdicts{
+		{Name: "a", Result: "Injection", Score: 0.75, Threshold: 0.5, Weight: 1},
+		{Name: "b", Result: "Injection", Score: 0.5, Threshold: 0.5, Weight: 1},
+		{Name: "c", Result: "Normal", Score: 0.25, Threshold: 0.4, Weight: 1},
+	}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		voters        models.MemberVerdicts
+		wantResult    string
+		wantScore     float64
+		wantThreshold float64
+	}{
+		{name: "weighted towards injection", strategy: EnsembleWeighted, voters: injectionHeavy, wantResult: "Injection", wantScore: 0.625, wantThreshold: 0.5},
+		{name: "weighted towards normal", strategy: EnsembleWeighted, voters: normalHeavy, wantResult: "Normal", wantScore: 0.375, wantThreshold: 0.5},
+		{name: "weighted is the default", strategy: "", voters: normalHeavy, wantResult: "Normal", wantScore: 0.375, wantThreshold: 0.5},
+		{name: "any injection", strategy: EnsembleAny, voters: normalHeavy, wantResult: "Injection", wantScore: 0.75, wantThreshold: 0.5},
+		{name: "any without injections", strategy: EnsembleAny, voters: normalHeavy[1:], wantResult: "Normal", wantScore: 0.25, wantThreshold: 0.5},
+		{name: "majority tie", strategy: EnsembleMajority, voters: injectionHeavy, wantResult: "Normal", wantScore: 0.5, wantThreshold: 0.5},
+		{name: "majority of injections", strategy: EnsembleMajority, voters: twoOfThree, wantResult: "Injection", wantScore: 0.5, wantThreshold: 0.4666666666666667},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&EnsembleClassifierRepository{strategy: tt.strategy}).vote(tt.voters)
+			if got.Result != tt.wantResult || math.Abs(got.Score-tt.wantScore) > 1e-9 || math.Abs(got.Threshold-tt.wantThreshold) > 1e-9 {
+				t.Errorf("vote() = %s %v (threshold %v), want %s %v (threshold %v)",
+					got.Result, got.Score, got.Threshold, tt.wantResult, tt.wantScore, tt.wantThreshold)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/internal/dto"
+)
+
+// heuristicPhrases maps common prompt injection phrases to the weight they add to the injection score.
+var heuristicPhrases = map[string]float64{
+	"ignore previous instructions": 0.8,
+	"ignore all previous":          0.8,
+	"ignore the above":             0.6,
+	"disregard previous":           0.7,
+	"disregard all":                0.5,
+	"forget all":                   0.5,
+	"forget your instructions":     0.7,
+	"predefined instructions":      0.4,
+	"system prompt":                0.4,
+	"your configuration":           0.3,
+	"reveal your":                  0.3,
+	"you are now":                  0.3,
+	"pretend to be":                0.3,
+	"act as":                       0.2,
+	"developer mode":               0.5,
+	"do anything now":              0.6,
+	"jailbreak":                    0.5,
+	"without any restrictions":     0.4,
+	"bypass":                       0.2,
+}
+
+const (
+	// heuristicThreshold is the minimal accumulated score for a text to be classified as an injection.
+	heuristicThreshold = 0.5
+	// heuristicModelVersion identifies the phrase list used by the heuristic engine.
+	heuristicModelVersion = "heuristic-v1"
+)
+
+// HeuristicClassifierRepository is a local classification engine that scores texts based on known injection phrases.
+// It does not require the internal classifier service and is meant as a lightweight alternative to the ML model.
+type HeuristicClassifierRepository struct {
+	logger *logrus.Logger
+}
+
+func NewHeuristicClassifierRepository(logger *logrus.Logger) *HeuristicClassifierRepository {
+	return &HeuristicClassifierRepository{logger: logger}
+}
+
+func (r *HeuristicClassifierRepository) Classify(_ context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	text := strings.ToLower(classificationRequest.Text)
+
+	// Accumulate the weights of all phrases found in the text.
+	var score float64
+	for phrase, weight := range heuristicPhrases {
+		if strings.Contains(text, phrase) {
+			score += weight
+		}
+	}
+
+	if score > 1 {
+		score = 1
+	}
+
+	result := dto.ClassifierResult{Result: "Normal", Score: score, Threshold: heuristicThreshold, ModelVersion: heuristicModelVersion}
+	if score >= heuristicThreshold {
+		result.Result = "Injection"
+	}
+
+	return result, nil
+}
@@ -2,66 +2,157 @@ package repository
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"llm-promp-inj.api/internal/dto"
 )
 
+// Base delay of the exponential backoff between retries of a failed classification request.
+const classifierRetryBaseDelay = 100 * time.Millisecond
+
+// errClassifierServer marks failures that are worth retrying (network errors and 5xx responses).
+var errClassifierServer = errors.New("internal classification service failure")
+
 type InternalClassifierAPIRepository struct {
-	apiPath string
-	logger  *logrus.Logger
+	apiPath        string
+	client         *http.Client
+	requestTimeout time.Duration
+	maxRetries     int
+	breaker        *CircuitBreaker
+	logger         *logrus.Logger
 }
 
-func NewInternalClassifierAPIRepository(apiPath string, logger *logrus.Logger) *InternalClassifierAPIRepository {
-	return &InternalClassifierAPIRepository{apiPath: apiPath, logger: logger}
+func NewInternalClassifierAPIRepository(apiPath string, requestTimeout time.Duration, maxRetries int, breaker *CircuitBreaker, logger *logrus.Logger) *InternalClassifierAPIRepository {
+	return &InternalClassifierAPIRepository{
+		apiPath:        apiPath,
+		client:         &http.Client{},
+		requestTimeout: requestTimeout,
+		maxRetries:     maxRetries,
+		breaker:        breaker,
+		logger:         logger,
+	}
 }
 
-func (r *InternalClassifierAPIRepository) SendClassificationRequest(classificationRequest dto.ClassificationRequest) (string, error) {
+// Classify sends the text to the internal classification service API.
+// Returns ErrCircuitOpen without calling the service while its circuit breaker is open.
+func (r *InternalClassifierAPIRepository) Classify(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	if !r.breaker.Allow() {
+		return dto.ClassifierResult{}, ErrCircuitOpen
+	}
+
+	classifierResult, err := r.SendClassificationRequest(ctx, classificationRequest)
+	switch {
+	case err == nil:
+		r.breaker.RecordSuccess()
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up, which says nothing about the service health.
+		r.breaker.RecordAbandoned()
+	case errors.Is(err, errClassifierServer):
+		r.breaker.RecordFailure()
+	default:
+		// The service answered but rejected the request.
+		r.breaker.RecordSuccess()
+	}
+
+	return classifierResult, err
+}
+
+// SendClassificationRequest performs the classification request and retries it with jittered exponential backoff on 5xx responses and network errors.
+func (r *InternalClassifierAPIRepository) SendClassificationRequest(ctx context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
 	// Marshal the classification request to JSON.
 	requestBody, err := json.Marshal(classificationRequest)
 	if err != nil {
 		r.logger.Error("Uanble to serialize the classification request into JSON before sending it to the internal classification service API. ERR: ", err)
-		return "", err
+		return dto.ClassifierResult{}, err
 	}
 
+	for attempt := 0; ; attempt++ {
+		classifierResult, err := r.sendOnce(ctx, requestBody)
+		if err == nil || !errors.Is(err, errClassifierServer) || attempt >= r.maxRetries || ctx.Err() != nil {
+			return classifierResult, err
+		}
+
+		// Full jitter spreads the retries of concurrent requests over the backoff window.
+		delay := time.Duration(rand.Int64N(int64(classifierRetryBaseDelay << attempt)))
+		r.logger.Warn("Retrying the request to the internal classification service in ", delay, ". ERR: ", err)
+
+		select {
+		case <-ctx.Done():
+			return dto.ClassifierResult{}, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// sendOnce performs a single classification request. Errors caused by the caller's context are returned as they are,
+// while the timeout of the request itself counts as a failure of the service.
+func (r *InternalClassifierAPIRepository) sendOnce(parentCtx context.Context, requestBody []byte) (dto.ClassifierResult, error) {
+	var classifierResult dto.ClassifierResult
+
+	ctx := parentCtx
+	if r.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parentCtx, r.requestTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.apiPath, bytes.NewReader(requestBody))
+	if err != nil {
+		r.logger.Error("Unable to create request to the internal classification service API. ERR: ", err)
+		return classifierResult, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
 	// Make the classification POST request to the internal classification service.
-	resp, err := http.Post(r.apiPath, "application/json", bytes.NewBuffer(requestBody))
+	resp, err := r.client.Do(req)
+	if err != nil && parentCtx.Err() != nil {
+		return classifierResult, parentCtx.Err()
+	}
 	if err != nil {
 		r.logger.Error("Unable to perform request to the internal classification service API. ERR: ", err)
-		return "", err
+		return classifierResult, fmt.Errorf("%w: %v", errClassifierServer, err)
 	}
 	defer resp.Body.Close()
 
 	// Check for non-200 HTTP response codes in case the classification failed.
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode >= http.StatusInternalServerError {
 		r.logger.Error("The internal classification service was unable to classify the request. Response status code: ", resp.StatusCode)
-		return "", errors.New("failed to send request: " + resp.Status)
+		return classifierResult, fmt.Errorf("%w: %s", errClassifierServer, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		r.logger.Error("The internal classification service rejected the request. Response status code: ", resp.StatusCode)
+		return classifierResult, errors.New("failed to send request: " + resp.Status)
 	}
 
 	// Read and parse the response to validate its integrity.
 	body, err := io.ReadAll(resp.Body)
+	if err != nil && parentCtx.Err() != nil {
+		return classifierResult, parentCtx.Err()
+	}
 	if err != nil {
-		r.logger.Error("Unable to process raw response body from the internal classification service. ERR: ", resp.StatusCode)
-		return "", err
+		r.logger.Error("Unable to process raw response body from the internal classification service. ERR: ", err)
+		return classifierResult, fmt.Errorf("%w: %v", errClassifierServer, err)
 	}
 
-	// Parse the response body into a map in order to access the "result" field.
-	var resultMap map[string]interface{}
-	if err := json.Unmarshal(body, &resultMap); err != nil {
+	// Parse the response body into the result, score, threshold and model version fields.
+	if err := json.Unmarshal(body, &classifierResult); err != nil {
 		r.logger.Error("Reesponse body from the internal classification service in not a valid JSON. ERR: ", resp.StatusCode)
-		return "", err
+		return classifierResult, err
 	}
 
-	// Access the "result" field directly and get the classification result. Can be either "Injection" or "Normal".
-	result, ok := resultMap["result"].(string)
-	if !ok {
+	// The "result" field is mandatory. Can be either "Injection" or "Normal".
+	if classifierResult.Result == "" {
 		r.logger.Error("Reesponse body from the internal classification service does not contain result field. ERR: ", resp.StatusCode)
-		return "", errors.New("result of internal classification API request is not a string")
+		return classifierResult, errors.New("result of internal classification API request is missing")
 	}
 
-	return result, nil
+	return classifierResult, nil
 }
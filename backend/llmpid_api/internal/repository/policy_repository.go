@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"llm-promp-inj.api/internal/models"
+)
+
+type PolicyRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewPolicyRepository(db *gorm.DB, logger *logrus.Logger) *PolicyRepository {
+	return &PolicyRepository{DB: db, logger: logger}
+}
+
+func (r *PolicyRepository) InsertPolicy(policy models.ClassificationPolicy) (models.ClassificationPolicy, error) {
+	if err := r.DB.Create(&policy).Error; err != nil {
+		r.logger.Error("Unable to insert classification policy into the database. ERR: ", err.Error())
+		return policy, errors.New("unable to insert object")
+	}
+
+	return policy, nil
+}
+
+// SelectPolicyBySourceName returns the policy of an external system of the tenant, or nil if the system has no policy.
+func (r *PolicyRepository) SelectPolicyBySourceName(tenantID uint, sourceName string) (*models.ClassificationPolicy, error) {
+	var policy models.ClassificationPolicy
+
+	err := r.DB.Scopes(tenantScope(tenantID)).Where("source_name = ?", sourceName).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve classification policy. ERR: ", err.Error())
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (r *PolicyRepository) SelectPolicies(tenantID uint) ([]models.ClassificationPolicy, error) {
+	var policies []models.ClassificationPolicy
+
+	if err := r.DB.Scopes(tenantScope(tenantID)).Order("source_name asc").Find(&policies).Error; err != nil {
+		r.logger.Error("Failed to retrieve classification policies. ERR: ", err.Error())
+		return policies, err
+	}
+
+	return policies, nil
+}
+
+func (r *PolicyRepository) UpdatePolicy(policy models.ClassificationPolicy) error {
+	err := r.DB.Model(&models.ClassificationPolicy{}).Scopes(tenantScope(policy.TenantID)).Where("id = ?", policy.ID).Updates(map[string]interface{}{
+		"threshold":       policy.Threshold,
+		"flag_threshold":  policy.FlagThreshold,
+		"block_threshold": policy.BlockThreshold,
+		"fail_mode":       policy.FailMode,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update classification policy. ERR: ", err.Error())
+		return errors.New("unable to update policy")
+	}
+
+	return nil
+}
+
+func (r *PolicyRepository) UpdateSourceName(tenantID uint, oldSourceName string, newSourceName string) error {
+	err := r.DB.Model(&models.ClassificationPolicy{}).Scopes(tenantScope(tenantID)).Where("source_name = ?", oldSourceName).Update("source_name", newSourceName).Error
+	if err != nil {
+		r.logger.Error("Unable to update the source name of a classification policy. ERR: ", err.Error())
+		return errors.New("unable to update policy")
+	}
+
+	return nil
+}
+
+func (r *PolicyRepository) DeleteBySourceName(tenantID uint, sourceName string) error {
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("source_name = ?", sourceName).Delete(&models.ClassificationPolicy{}).Error; err != nil {
+		r.logger.Error("Failed to delete classification policy from database. ERR: ", err.Error())
+		return errors.New("unable to delete object")
+	}
+
+	return nil
+}
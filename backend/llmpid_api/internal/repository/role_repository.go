@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/internal/models"
+)
+
+type RoleRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewRoleRepository(db *gorm.DB, logger *logrus.Logger) *RoleRepository {
+	return &RoleRepository{DB: db, logger: logger}
+}
+
+func (r *RoleRepository) InsertRole(role models.Role) (models.Role, error) {
+	if err := r.DB.Create(&role).Error; err != nil {
+		r.logger.Error("Unable to insert role into the database. ERR: ", err.Error())
+		return role, errors.New("unable to insert object")
+	}
+
+	return role, nil
+}
+
+// UpsertRole creates the role or overwrites the description, permissions and built-in flag of an existing role with the same name.
+func (r *RoleRepository) UpsertRole(role models.Role) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "permissions", "built_in", "updated_at"}),
+	}).Create(&role).Error
+	if err != nil {
+		r.logger.Error("Unable to upsert role. ERR: ", err.Error())
+		return errors.New("unable to upsert role")
+	}
+
+	return nil
+}
+
+// SelectRoleByName returns a role, or nil if there is no such role.
+func (r *RoleRepository) SelectRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+
+	err := r.DB.Where("name = ?", name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve role. ERR: ", err.Error())
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (r *RoleRepository) SelectRoles() ([]models.Role, error) {
+	var roles []models.Role
+
+	if err := r.DB.Order("name asc").Find(&roles).Error; err != nil {
+		r.logger.Error("Failed to retrieve roles. ERR: ", err.Error())
+		return roles, err
+	}
+
+	return roles, nil
+}
+
+func (r *RoleRepository) UpdateRole(role models.Role) error {
+	err := r.DB.Model(&models.Role{}).Where("id = ?", role.ID).Updates(map[string]interface{}{
+		"description": role.Description,
+		"permissions": role.Permissions,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update role. ERR: ", err.Error())
+		return errors.New("unable to update role")
+	}
+
+	return nil
+}
+
+func (r *RoleRepository) DeleteRoleByName(name string) error {
+	if err := r.DB.Where("name = ?", name).Delete(&models.Role{}).Error; err != nil {
+		r.logger.Error("Failed to delete role from database. ERR: ", err.Error())
+		return errors.New("unable to delete object")
+	}
+
+	return nil
+}
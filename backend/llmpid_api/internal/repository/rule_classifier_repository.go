@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"llm-promp-inj.api/internal/dto"
+)
+
+// RuleClassifierRepository is a classification engine backed by the detection rules, meant as a member of an ensemble.
+// A text matching any enabled rule is an injection with the highest score of the matched rules.
+type RuleClassifierRepository struct {
+	Rules *RuleRepository
+}
+
+func NewRuleClassifierRepository(rules *RuleRepository) *RuleClassifierRepository {
+	return &RuleClassifierRepository{Rules: rules}
+}
+
+func (r *RuleClassifierRepository) Classify(_ context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	result := dto.ClassifierResult{Result: "Normal", Threshold: 0.5, ModelVersion: r.Rules.Version()}
+
+	for _, rule := range r.Rules.Match(classificationRequest.Text) {
+		result.Result = "Injection"
+		result.Score = max(result.Score, rule.Score)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,394 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"llm-promp-inj.api/internal/models"
+)
+
+// rulesFile is the layout of the YAML (or JSON) file containing the detection rules.
+type rulesFile struct {
+	Rules []models.DetectionRule `yaml:"rules"`
+}
+
+// compiledRule is a detection rule prepared for matching.
+type compiledRule struct {
+	rule    models.DetectionRule
+	pattern *regexp.Regexp
+	phrases []string
+}
+
+// RuleRepository combines the detection rules of the rules file with the rules stored in the database, which take precedence
+// over file rules with the same ID, and keeps the compiled rules in memory for matching. The rules are reloaded periodically,
+// so changes of the file and changes made through any replica are picked up by all of them.
+type RuleRepository struct {
+	DB       *gorm.DB
+	filePath string
+	logger   *logrus.Logger
+
+	mu      sync.RWMutex
+	rules   []compiledRule
+	version string
+
+	fileMu      sync.Mutex
+	fileRules   []models.DetectionRule
+	fileModTime time.Time
+	fileErr     string
+}
+
+func NewRuleRepository(db *gorm.DB, filePath string, logger *logrus.Logger) *RuleRepository {
+	r := &RuleRepository{DB: db, filePath: filePath, logger: logger}
+	if err := r.Reload(); err != nil {
+		logger.Warn("Unable to load detection rules. Starting with the rules of the rules file. ERR: ", err)
+	}
+
+	return r
+}
+
+// Reload reads the rules file, if it changed, and the rules from the database, and activates them if they changed.
+// Rules of the database that cannot be compiled are skipped. If the database cannot be read, the current rules are kept.
+func (r *RuleRepository) Reload() error {
+	fileRules := r.loadRulesFile()
+
+	var dbRules []models.DetectionRule
+	if err := r.DB.Order("id ASC").Find(&dbRules).Error; err != nil {
+		r.logger.Error("Unable to select detection rules. ERR: ", err.Error())
+		// Without any rules loaded yet, e.g. on startup, the rules of the file are used in the meantime.
+		if r.Version() == "" {
+			r.activate(mergeRules(fileRules, nil))
+		}
+		return errors.New("unable to select detection rules")
+	}
+	r.activate(mergeRules(fileRules, dbRules))
+
+	return nil
+}
+
+// activate compiles the rules and replaces the current rules with them, if they differ.
+func (r *RuleRepository) activate(rules []models.DetectionRule) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		compiledRule, err := compileRule(rule)
+		if err != nil {
+			r.logger.Error("Unable to compile detection rule ", rule.ID, ". ERR: ", err.Error())
+			continue
+		}
+		compiled = append(compiled, compiledRule)
+	}
+	version := rulesVersion(compiled)
+
+	r.mu.Lock()
+	changed := version != r.version
+	if changed {
+		r.rules = compiled
+		r.version = version
+	}
+	r.mu.Unlock()
+
+	if changed {
+		r.logger.Info("Loaded ", len(compiled), " detection rules.")
+	}
+}
+
+// loadRulesFile returns the rules of the rules file. The file is only read again once it was modified, and if it cannot be read
+// or contains an invalid rule, the rules it contained before are kept.
+func (r *RuleRepository) loadRulesFile() []models.DetectionRule {
+	if r.filePath == "" {
+		return nil
+	}
+
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	rules, modTime, err := readRulesFile(r.filePath, r.fileModTime)
+	if err != nil {
+		// The error is only logged once, since the file is checked on every reload.
+		if err.Error() != r.fileErr {
+			r.logger.Error("Unable to load the rules file. Keeping its previous rules. ERR: ", err)
+			r.fileErr = err.Error()
+		}
+		return r.fileRules
+	}
+	r.fileErr = ""
+
+	if !modTime.Equal(r.fileModTime) {
+		r.fileRules = rules
+		r.fileModTime = modTime
+	}
+
+	return r.fileRules
+}
+
+// readRulesFile reads and validates the rules of the rules file, unless its modification time still equals modTime.
+func readRulesFile(filePath string, modTime time.Time) ([]models.DetectionRule, time.Time, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, modTime, err
+	}
+	if info.ModTime().Equal(modTime) {
+		return nil, modTime, nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, modTime, err
+	}
+
+	// YAML is a superset of JSON, so JSON rule files are read the same way.
+	var file rulesFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, modTime, err
+	}
+
+	ids := make(map[string]bool, len(file.Rules))
+	for i, rule := range file.Rules {
+		if _, err := compileRule(rule); err != nil {
+			return nil, modTime, err
+		}
+		if ids[rule.ID] {
+			return nil, modTime, fmt.Errorf("duplicate rule ID %s", rule.ID)
+		}
+		ids[rule.ID] = true
+		file.Rules[i].Source = models.RuleSourceFile
+	}
+
+	return file.Rules, info.ModTime(), nil
+}
+
+// mergeRules combines the rules of the rules file with the rules of the database, which replace file rules with the same ID.
+// The rules are ordered by their ID.
+func mergeRules(fileRules []models.DetectionRule, dbRules []models.DetectionRule) []models.DetectionRule {
+	rulesByID := make(map[string]models.DetectionRule, len(fileRules)+len(dbRules))
+	for _, rule := range fileRules {
+		rulesByID[rule.ID] = rule
+	}
+	for _, rule := range dbRules {
+		rule.Source = models.RuleSourceDatabase
+		rulesByID[rule.ID] = rule
+	}
+
+	rules := make([]models.DetectionRule, 0, len(rulesByID))
+	for _, rule := range rulesByID {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	return rules
+}
+
+// WatchForChanges periodically reloads the rules file and the rules in the database. A non-positive interval disables the reload.
+func (r *RuleRepository) WatchForChanges(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Unable to reload detection rules. Keeping the current rules. ERR: ", err)
+			}
+		}
+	}()
+}
+
+// Match returns the enabled rules matching any of the texts.
+func (r *RuleRepository) Match(texts ...string) []models.DetectionRule {
+	var matched []models.DetectionRule
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rule := range r.rules {
+		if rule.rule.Disabled {
+			continue
+		}
+
+		for _, text := range texts {
+			if rule.matches(text) {
+				matched = append(matched, rule.rule)
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+// Version identifies the current rule set. It changes whenever a rule is added, changed or removed.
+func (r *RuleRepository) Version() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.version
+}
+
+// SelectRules returns the rules of the database along with the rules of the rules file they do not replace.
+func (r *RuleRepository) SelectRules() ([]models.DetectionRule, error) {
+	var rules []models.DetectionRule
+
+	if err := r.DB.Order("id ASC").Find(&rules).Error; err != nil {
+		r.logger.Error("Failed to retrieve detection rules. ERR: ", err.Error())
+		return rules, err
+	}
+
+	return mergeRules(r.loadRulesFile(), rules), nil
+}
+
+// SelectRuleByID returns the rule with the ID from the database or, if there is none, from the rules file. It returns nil if neither has the rule.
+func (r *RuleRepository) SelectRuleByID(id string) (*models.DetectionRule, error) {
+	var rule models.DetectionRule
+
+	err := r.DB.Where("id = ?", id).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		for _, fileRule := range r.loadRulesFile() {
+			if fileRule.ID == id {
+				return &fileRule, nil
+			}
+		}
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve detection rule. ERR: ", err.Error())
+		return nil, err
+	}
+	rule.Source = models.RuleSourceDatabase
+
+	return &rule, nil
+}
+
+// reloadAfterWrite activates a stored change on this replica right away. If the rules cannot be reloaded, the change is still stored
+// and activated by the next periodic reload, so the error is only logged.
+func (r *RuleRepository) reloadAfterWrite() {
+	if err := r.Reload(); err != nil {
+		r.logger.Warn("Detection rule change was stored, but not activated yet. It is activated on the next reload. ERR: ", err)
+	}
+}
+
+// InsertRule validates and stores a rule, and activates it on this replica right away.
+func (r *RuleRepository) InsertRule(rule models.DetectionRule) error {
+	if _, err := compileRule(rule); err != nil {
+		return err
+	}
+
+	if err := r.DB.Create(&rule).Error; err != nil {
+		r.logger.Error("Unable to insert detection rule into the database. ERR: ", err.Error())
+		return errors.New("unable to insert object")
+	}
+
+	r.reloadAfterWrite()
+	return nil
+}
+
+// UpdateRule validates and stores a rule, and activates it on this replica right away.
+func (r *RuleRepository) UpdateRule(rule models.DetectionRule) error {
+	if _, err := compileRule(rule); err != nil {
+		return err
+	}
+
+	err := r.DB.Model(&models.DetectionRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+		"description": rule.Description,
+		"type":        rule.Type,
+		"pattern":     rule.Pattern,
+		"phrases":     rule.Phrases,
+		"score":       rule.Score,
+		"action":      rule.Action,
+		"disabled":    rule.Disabled,
+	}).Error
+	if err != nil {
+		r.logger.Error("Unable to update detection rule. ERR: ", err.Error())
+		return errors.New("unable to update rule")
+	}
+
+	r.reloadAfterWrite()
+	return nil
+}
+
+func (r *RuleRepository) DeleteRule(id string) error {
+	if err := r.DB.Where("id = ?", id).Delete(&models.DetectionRule{}).Error; err != nil {
+		r.logger.Error("Failed to delete detection rule from database. ERR: ", err.Error())
+		return errors.New("unable to delete object")
+	}
+
+	r.reloadAfterWrite()
+	return nil
+}
+
+// compileRule validates a rule and prepares it for matching.
+func compileRule(rule models.DetectionRule) (compiledRule, error) {
+	if rule.ID == "" {
+		return compiledRule{}, errors.New("rule ID is required")
+	}
+	if rule.Score < 0 || rule.Score > 1 {
+		return compiledRule{}, fmt.Errorf("rule %s: score must be between 0 and 1", rule.ID)
+	}
+	if rule.Score == 0 {
+		rule.Score = 1
+	}
+	if rule.Action != "" && rule.Action != models.ActionBlock {
+		return compiledRule{}, fmt.Errorf("rule %s: action must be empty or %q", rule.ID, models.ActionBlock)
+	}
+
+	compiled := compiledRule{rule: rule}
+
+	switch rule.Type {
+	case models.RuleTypeRegex:
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil || rule.Pattern == "" {
+			return compiledRule{}, fmt.Errorf("rule %s: invalid pattern", rule.ID)
+		}
+		compiled.pattern = pattern
+	case models.RuleTypePhrases:
+		for _, phrase := range rule.Phrases {
+			if phrase = strings.TrimSpace(phrase); phrase != "" {
+				compiled.phrases = append(compiled.phrases, strings.ToLower(phrase))
+			}
+		}
+		if len(compiled.phrases) == 0 {
+			return compiledRule{}, fmt.Errorf("rule %s: at least one phrase is required", rule.ID)
+		}
+	default:
+		return compiledRule{}, fmt.Errorf("rule %s: unknown rule type %s", rule.ID, rule.Type)
+	}
+
+	return compiled, nil
+}
+
+// rulesVersion hashes the rules into a short identifier of the rule set.
+func rulesVersion(rules []compiledRule) string {
+	hash := sha256.New()
+	for _, rule := range rules {
+		fmt.Fprintf(hash, "%+v\n", rule.rule)
+	}
+
+	return "rules-" + hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func (c compiledRule) matches(text string) bool {
+	if c.pattern != nil {
+		return c.pattern.MatchString(text)
+	}
+
+	lowerText := strings.ToLower(text)
+	for _, phrase := range c.phrases {
+		if strings.Contains(lowerText, phrase) {
+			return true
+		}
+	}
+
+	return false
+}
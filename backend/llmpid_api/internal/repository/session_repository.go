@@ -18,8 +18,8 @@ func NewSessionRepository(db *gorm.DB, logger *logrus.Logger) *SessionRepository
 	return &SessionRepository{DB: db, logger: logger}
 }
 
-func (r *SessionRepository) CreateSession(sessionID string, sub string, expiresAt int64) error {
-	session := models.Session{SessionID: sessionID, Sub: sub, ExpiresAt: expiresAt}
+func (r *SessionRepository) CreateSession(sessionID string, sub string, tenantID uint, expiresAt int64) error {
+	session := models.Session{SessionID: sessionID, Sub: sub, TenantID: tenantID, ExpiresAt: expiresAt}
 
 	err := r.DB.Create(&session).Error
 	if err != nil {
@@ -30,43 +30,33 @@ func (r *SessionRepository) CreateSession(sessionID string, sub string, expiresA
 	return nil
 }
 
-func (r *SessionRepository) IsValidSession(sessionID string, sub string) bool {
-	session, _ := r.SelectSessionBySIDAndSub(sessionID, sub)
-	if (models.Session{} == session) {
+// IsValidSession reports whether the session exists within the tenant the token was issued for and has not expired.
+func (r *SessionRepository) IsValidSession(sessionID string, sub string, tenantID uint) bool {
+	session, err := r.SelectSessionBySIDAndSub(tenantID, sessionID, sub)
+	if err != nil {
 		return false
 	}
 
 	if time.Now().Unix() > session.ExpiresAt {
-		r.DeleteSessionBySub(sessionID)
+		r.DeleteSessionBySID(tenantID, sessionID)
 		return false
 	}
 
 	return true
 }
 
-func (r *SessionRepository) DeleteSessionBySID(sid string) {
-	r.DB.Where("session_id = ?", sid).Delete(&models.Session{})
-}
-
-func (r *SessionRepository) DeleteSessionBySub(sub string) {
-	r.DB.Where("sub = ?", sub).Delete(&models.Session{})
+func (r *SessionRepository) DeleteSessionBySID(tenantID uint, sid string) {
+	r.DB.Scopes(tenantScope(tenantID)).Where("session_id = ?", sid).Delete(&models.Session{})
 }
 
-func (r *SessionRepository) SelectSessionBySub(sub string) (models.Session, error) {
-	var session models.Session
-
-	err := r.DB.Where("sub = ?", sub).First(&session).Error
-	if err != nil {
-		return models.Session{}, err
-	}
-
-	return session, nil
+func (r *SessionRepository) DeleteSessionBySub(tenantID uint, sub string) {
+	r.DB.Scopes(tenantScope(tenantID)).Where("sub = ?", sub).Delete(&models.Session{})
 }
 
-func (r *SessionRepository) SelectSessionBySIDAndSub(sid string, sub string) (models.Session, error) {
+func (r *SessionRepository) SelectSessionBySIDAndSub(tenantID uint, sid string, sub string) (models.Session, error) {
 	var session models.Session
 
-	err := r.DB.Where("session_id = ?", sid).Where("sub = ?", sub).First(&session).Error
+	err := r.DB.Scopes(tenantScope(tenantID)).Where("session_id = ?", sid).Where("sub = ?", sub).First(&session).Error
 	if err != nil {
 		r.logger.Error("Unable to find session by session_id and sub. ERR: ", err)
 		return session, err
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"llm-promp-inj.api/config"
+)
+
+// encryptedKeyPrefix marks private keys that are encrypted with the key encryption key. Other stored keys are plaintext PEM.
+const encryptedKeyPrefix = "aes-gcm:"
+
+// keyEncryptionKeySize is the size of the AES-256 key encryption key in bytes.
+const keyEncryptionKeySize = 32
+
+// loadKeyEncryptionKey returns the base64 encoded key encryption key from the configuration or, if it is not set, from the configured file.
+// It returns nil if neither is configured.
+func loadKeyEncryptionKey(cfg config.TokenConfiguration) ([]byte, error) {
+	encodedKey := cfg.KeyEncryptionKey
+	if encodedKey == "" && cfg.KeyEncryptionKeyPath != "" {
+		content, err := os.ReadFile(cfg.KeyEncryptionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		encodedKey = string(content)
+	}
+	encodedKey = strings.TrimSpace(encodedKey)
+	if encodedKey == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != keyEncryptionKeySize {
+		return nil, fmt.Errorf("the key encryption key must be %d base64 encoded bytes", keyEncryptionKeySize)
+	}
+
+	return key, nil
+}
+
+// encryptPrivateKey seals a PEM encoded private key with AES-GCM. The kid is authenticated along with it,
+// so an encrypted key cannot be moved to another row.
+func encryptPrivateKey(keyEncryptionKey []byte, kid string, privateKeyPEM string) (string, error) {
+	aead, err := newKeyEncryptionAEAD(keyEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(privateKeyPEM), []byte(kid))
+
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptPrivateKey returns the PEM encoded private key of a stored key. Plaintext keys, e.g. stored before a key encryption key
+// was configured, are returned as they are.
+func decryptPrivateKey(keyEncryptionKey []byte, kid string, storedKey string) (string, error) {
+	if !strings.HasPrefix(storedKey, encryptedKeyPrefix) {
+		return storedKey, nil
+	}
+	if keyEncryptionKey == nil {
+		return "", errors.New("the key is encrypted, but no key encryption key is configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(storedKey, encryptedKeyPrefix))
+	if err != nil {
+		return "", err
+	}
+	aead, err := newKeyEncryptionAEAD(keyEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted key is too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(kid))
+	if err != nil {
+		return "", errors.New("the key cannot be decrypted with the configured key encryption key")
+	}
+
+	return string(plaintext), nil
+}
+
+func newKeyEncryptionAEAD(keyEncryptionKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(keyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,483 @@
+package repository
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/models"
+)
+
+// Supported algorithms of the token signing keys.
+const (
+	SigningAlgorithmEdDSA = "EdDSA"
+	SigningAlgorithmRS256 = "RS256"
+)
+
+// signingKeysLockID identifies the advisory lock that serializes the rotation of the signing keys across replicas.
+const signingKeysLockID = 7140125
+
+// rsaKeyBits is the size of generated RSA keys and the minimum size of RSA keys read from files.
+const rsaKeyBits = 2048
+
+// VerificationKey is the public part of a signing key, identified by the "kid" header of the tokens it signed.
+type VerificationKey struct {
+	Kid       string
+	Algorithm string
+	PublicKey crypto.PublicKey
+}
+
+type signingKey struct {
+	VerificationKey
+	privateKey  crypto.Signer // Nil for keys that only verify tokens.
+	activatesAt time.Time
+	expiresAt   time.Time // Zero for keys that do not expire.
+}
+
+// SigningKeyRepository holds the keys that sign and verify the access tokens.
+// The keys are read from PEM files if a private key file is configured. Otherwise, they are generated and stored in the database,
+// so they are shared by replicas and survive restarts, and they are rotated in the configured interval.
+// Stored private keys are encrypted with the key encryption key, which is required outside of the development environment.
+type SigningKeyRepository struct {
+	DB     *gorm.DB
+	cfg    config.TokenConfiguration
+	logger *logrus.Logger
+
+	keyEncryptionKey []byte // Nil if the stored private keys are not encrypted.
+
+	mu   sync.RWMutex
+	keys []signingKey // Sorted by activation.
+}
+
+func NewSigningKeyRepository(db *gorm.DB, cfg config.TokenConfiguration, environment string, logger *logrus.Logger) (*SigningKeyRepository, error) {
+	r := &SigningKeyRepository{DB: db, cfg: cfg, logger: logger}
+
+	if cfg.PrivateKeyPath != "" {
+		if err := r.loadFromFiles(); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if cfg.Algorithm != SigningAlgorithmEdDSA && cfg.Algorithm != SigningAlgorithmRS256 {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", cfg.Algorithm)
+	}
+
+	keyEncryptionKey, err := loadKeyEncryptionKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if keyEncryptionKey == nil {
+		if environment != "development" {
+			return nil, errors.New("stored signing keys must be encrypted: set TOKEN_KEY_ENCRYPTION_KEY or keyEncryptionKeyPath, or configure privateKeyPath")
+		}
+		logger.Warn("No key encryption key is configured. Signing keys are stored unencrypted.")
+	}
+	r.keyEncryptionKey = keyEncryptionKey
+	if err := r.Rotate(); err != nil {
+		return nil, err
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// activeKey returns the most recently activated key.
+func (r *SigningKeyRepository) activeKey() (signingKey, error) {
+	now := time.Now()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		key := r.keys[i]
+		if key.privateKey != nil && !key.activatesAt.After(now) {
+			return key, nil
+		}
+	}
+
+	return signingKey{}, errors.New("no active signing key")
+}
+
+// VerificationKey returns the key identified by the kid, unless it has expired.
+func (r *SigningKeyRepository) VerificationKey(kid string) (VerificationKey, bool) {
+	now := time.Now()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Kid == kid && (key.expiresAt.IsZero() || now.Before(key.expiresAt)) {
+			return key.VerificationKey, true
+		}
+	}
+
+	return VerificationKey{}, false
+}
+
+// VerificationKeys returns all keys that verify tokens, including keys that are published but not yet activated.
+func (r *SigningKeyRepository) VerificationKeys() []VerificationKey {
+	now := time.Now()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]VerificationKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.expiresAt.IsZero() || now.Before(key.expiresAt) {
+			keys = append(keys, key.VerificationKey)
+		}
+	}
+
+	return keys
+}
+
+// Reload reads the keys from the database. Keys that cannot be parsed are skipped.
+// Keys read from files are not reloaded.
+func (r *SigningKeyRepository) Reload() error {
+	if r.cfg.PrivateKeyPath != "" {
+		return nil
+	}
+
+	var storedKeys []models.SigningKey
+	if err := r.DB.Order("activates_at ASC").Find(&storedKeys).Error; err != nil {
+		r.logger.Error("Unable to select signing keys. ERR: ", err.Error())
+		return errors.New("unable to select signing keys")
+	}
+
+	retention := time.Duration(r.cfg.RetiredKeyRetention) * time.Hour
+	keys := make([]signingKey, 0, len(storedKeys))
+	for _, storedKey := range storedKeys {
+		privateKeyPEM, err := decryptPrivateKey(r.keyEncryptionKey, storedKey.Kid, storedKey.PrivateKey)
+		if err != nil {
+			r.logger.Error("Unable to decrypt signing key ", storedKey.Kid, ". ERR: ", err.Error())
+			continue
+		}
+		privateKey, err := parsePrivateKeyPEM([]byte(privateKeyPEM))
+		if err != nil {
+			r.logger.Error("Unable to parse signing key ", storedKey.Kid, ". ERR: ", err.Error())
+			continue
+		}
+
+		key := signingKey{
+			VerificationKey: VerificationKey{Kid: storedKey.Kid, Algorithm: storedKey.Algorithm, PublicKey: privateKey.Public()},
+			privateKey:      privateKey,
+			activatesAt:     storedKey.ActivatesAt,
+		}
+		if storedKey.RetiresAt != nil {
+			key.expiresAt = storedKey.RetiresAt.Add(retention)
+		}
+		keys = append(keys, key)
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Rotate generates the first signing key or, once the newest key is due for rotation, its successor.
+// The successor is published RotationOverlap hours before it replaces the current key, so verifiers that cache the published keys
+// learn it in time. A change of the configured algorithm is also rotated. Keys whose retention has ended are deleted,
+// and keys that were stored before a key encryption key was configured are encrypted.
+func (r *SigningKeyRepository) Rotate() error {
+	now := time.Now()
+	interval := time.Duration(r.cfg.RotationInterval) * time.Hour
+	overlap := time.Duration(r.cfg.RotationOverlap) * time.Hour
+	retention := time.Duration(r.cfg.RetiredKeyRetention) * time.Hour
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Keeps replicas from generating a successor at the same time.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeysLockID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("retires_at < ?", now.Add(-retention)).Delete(&models.SigningKey{}).Error; err != nil {
+			return err
+		}
+		if err := r.encryptPlaintextKeys(tx); err != nil {
+			return err
+		}
+
+		activatesAt := now
+		var newestKey models.SigningKey
+		err := tx.Order("activates_at DESC").Take(&newestKey).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			var due bool
+			activatesAt, due = successorActivation(newestKey, r.cfg.Algorithm, now, interval, overlap)
+			if !due {
+				return nil
+			}
+			if err := tx.Model(&newestKey).Update("retires_at", activatesAt).Error; err != nil {
+				return err
+			}
+		}
+
+		key, err := generateSigningKey(r.cfg.Algorithm, activatesAt)
+		if err != nil {
+			return err
+		}
+		if r.keyEncryptionKey != nil {
+			if key.PrivateKey, err = encryptPrivateKey(r.keyEncryptionKey, key.Kid, key.PrivateKey); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+
+		r.logger.Info("Generated signing key ", key.Kid, " activating at ", activatesAt.Format(time.RFC3339), ".")
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Unable to rotate signing keys. ERR: ", err.Error())
+		return errors.New("unable to rotate signing keys")
+	}
+
+	return nil
+}
+
+// successorActivation reports whether the newest key is due to be replaced and when its successor activates.
+// A key is due once its rotation interval has passed, less the overlap in which the successor is published, or once the algorithm changed.
+// The successor activates after the overlap, but not before the interval of the newest key has passed. A retired key already has a successor.
+func successorActivation(newestKey models.SigningKey, algorithm string, now time.Time, interval time.Duration, overlap time.Duration) (time.Time, bool) {
+	if newestKey.RetiresAt != nil {
+		return time.Time{}, false
+	}
+
+	algorithmChanged := newestKey.Algorithm != algorithm
+	due := interval > 0 && !now.Before(newestKey.ActivatesAt.Add(interval-overlap))
+	if !algorithmChanged && !due {
+		return time.Time{}, false
+	}
+
+	activatesAt := now.Add(overlap)
+	if due && activatesAt.Before(newestKey.ActivatesAt.Add(interval)) {
+		activatesAt = newestKey.ActivatesAt.Add(interval)
+	}
+
+	return activatesAt, true
+}
+
+// encryptPlaintextKeys encrypts the stored private keys that are not yet encrypted, if a key encryption key is configured.
+func (r *SigningKeyRepository) encryptPlaintextKeys(tx *gorm.DB) error {
+	if r.keyEncryptionKey == nil {
+		return nil
+	}
+
+	var plaintextKeys []models.SigningKey
+	if err := tx.Where("private_key NOT LIKE ?", encryptedKeyPrefix+"%").Find(&plaintextKeys).Error; err != nil {
+		return err
+	}
+	for _, plaintextKey := range plaintextKeys {
+		encryptedKey, err := encryptPrivateKey(r.keyEncryptionKey, plaintextKey.Kid, plaintextKey.PrivateKey)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&plaintextKey).Update("private_key", encryptedKey).Error; err != nil {
+			return err
+		}
+		r.logger.Info("Encrypted signing key ", plaintextKey.Kid, ".")
+	}
+
+	return nil
+}
+
+// WatchForChanges periodically rotates the keys when due and reloads them, so all replicas pick up the keys generated by any of them.
+// A non-positive interval and keys read from files disable the rotation.
+func (r *SigningKeyRepository) WatchForChanges(interval time.Duration) {
+	if interval <= 0 || r.cfg.PrivateKeyPath != "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := r.Rotate(); err != nil {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Unable to reload signing keys. Keeping the current keys. ERR: ", err)
+			}
+		}
+	}()
+}
+
+// loadFromFiles reads the configured private key and the public keys that still verify tokens.
+func (r *SigningKeyRepository) loadFromFiles() error {
+	content, err := os.ReadFile(r.cfg.PrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	privateKey, err := parsePrivateKeyPEM(content)
+	if err != nil {
+		return fmt.Errorf("invalid signing key %s: %w", r.cfg.PrivateKeyPath, err)
+	}
+	signingVerificationKey, err := newVerificationKey(privateKey.Public())
+	if err != nil {
+		return fmt.Errorf("invalid signing key %s: %w", r.cfg.PrivateKeyPath, err)
+	}
+
+	keys := make([]signingKey, 0, len(r.cfg.VerificationKeyPaths)+1)
+	for _, path := range r.cfg.VerificationKeyPaths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		publicKey, err := parsePublicKeyPEM(content)
+		if err != nil {
+			return fmt.Errorf("invalid verification key %s: %w", path, err)
+		}
+		verificationKey, err := newVerificationKey(publicKey)
+		if err != nil {
+			return fmt.Errorf("invalid verification key %s: %w", path, err)
+		}
+		keys = append(keys, signingKey{VerificationKey: verificationKey})
+	}
+	keys = append(keys, signingKey{VerificationKey: signingVerificationKey, privateKey: privateKey})
+
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+
+	return nil
+}
+
+// generateSigningKey generates a key pair for the algorithm and encodes it for storage.
+func generateSigningKey(algorithm string, activatesAt time.Time) (models.SigningKey, error) {
+	var privateKey crypto.Signer
+	switch algorithm {
+	case SigningAlgorithmEdDSA:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return models.SigningKey{}, err
+		}
+		privateKey = edKey
+	case SigningAlgorithmRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return models.SigningKey{}, err
+		}
+		privateKey = rsaKey
+	default:
+		return models.SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	kid, err := keyID(privateKey.Public())
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	return models.SigningKey{
+		Kid:         kid,
+		Algorithm:   algorithm,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// newVerificationKey derives the algorithm and kid of a public key.
+func newVerificationKey(publicKey crypto.PublicKey) (VerificationKey, error) {
+	var algorithm string
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		algorithm = SigningAlgorithmEdDSA
+	case *rsa.PublicKey:
+		if key.N.BitLen() < rsaKeyBits {
+			return VerificationKey{}, fmt.Errorf("RSA keys must have at least %d bits", rsaKeyBits)
+		}
+		algorithm = SigningAlgorithmRS256
+	default:
+		return VerificationKey{}, errors.New("unsupported key type, expected an Ed25519 or RSA key")
+	}
+
+	kid, err := keyID(publicKey)
+	if err != nil {
+		return VerificationKey{}, err
+	}
+
+	return VerificationKey{Kid: kid, Algorithm: algorithm, PublicKey: publicKey}, nil
+}
+
+// keyID is the base64url encoded SHA-256 hash of the DER encoded public key, so every replica derives the same kid for the same key.
+func keyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// parsePrivateKeyPEM parses a PKCS #8 ("PRIVATE KEY") or PKCS #1 ("RSA PRIVATE KEY") encoded private key.
+func parsePrivateKeyPEM(content []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// parsePublicKeyPEM parses a PKIX ("PUBLIC KEY") encoded public key.
+func parsePublicKeyPEM(content []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/internal/dto"
+)
+
+// StubClassifierRepository is a classification engine that always returns the same configured result.
+// It is meant for tests and local development without the internal classifier service.
+type StubClassifierRepository struct {
+	result string
+	logger *logrus.Logger
+}
+
+func NewStubClassifierRepository(result string, logger *logrus.Logger) *StubClassifierRepository {
+	// Default to a benign result if the stub was not configured.
+	if result == "" {
+		result = "Normal"
+	}
+
+	return &StubClassifierRepository{result: result, logger: logger}
+}
+
+func (r *StubClassifierRepository) Classify(_ context.Context, classificationRequest dto.ClassificationRequest) (dto.ClassifierResult, error) {
+	r.logger.Debug("Stub classifier engine returned a fixed result: ", r.result)
+
+	result := dto.ClassifierResult{Result: r.result, Threshold: 0.5, ModelVersion: "stub"}
+	if r.result == "Injection" {
+		result.Score = 1
+	}
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/internal/models"
+)
+
+// Settings of a query that tell the tenant scope callback which rows of the tenants it may access.
+const (
+	tenantIDSetting   = "tenant_scope:tenant_id"
+	allTenantsSetting = "tenant_scope:all_tenants"
+)
+
+var errMissingTenantScope = errors.New("query on data owned by tenants without a tenant scope")
+
+// tenantScope restricts a query to the rows of a tenant. Every query on data owned by a tenant must apply it, or allTenants.
+// Tenant IDs start at 1, so a missing tenant (0) matches no rows.
+func tenantScope(tenantID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(tenantIDSetting, tenantID)
+	}
+}
+
+// allTenants lets a query access the rows of all tenants. It is only meant for queries that do not act on behalf of a tenant,
+// such as logging in by a username, which is unique across tenants, or processing the jobs of all tenants in the background.
+func allTenants(db *gorm.DB) *gorm.DB {
+	return db.Set(allTenantsSetting, true)
+}
+
+// RegisterTenantScope registers the callbacks that restrict every query, update and delete on a table with a tenant_id column
+// to the tenant set by tenantScope. Such statements fail unless they apply tenantScope or allTenants, so no query can forget the scope.
+// Raw SQL statements are not checked.
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant_scope:query", applyTenantScope); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant_scope:row", applyTenantScope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant_scope:update", applyTenantScope); err != nil {
+		return err
+	}
+
+	return callbacks.Delete().Before("gorm:delete").Register("tenant_scope:delete", applyTenantScope)
+}
+
+func applyTenantScope(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.LookUpField("tenant_id") == nil {
+		return
+	}
+
+	if tenantID, ok := db.Get(tenantIDSetting); ok {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, Value: tenantID},
+		}})
+		return
+	}
+	if _, ok := db.Get(allTenantsSetting); !ok {
+		db.AddError(errMissingTenantScope)
+	}
+}
+
+type TenantRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewTenantRepository(db *gorm.DB, logger *logrus.Logger) *TenantRepository {
+	return &TenantRepository{DB: db, logger: logger}
+}
+
+// InsertTenantWithAdmin creates a tenant along with its first admin account, so no tenant is left without an admin.
+func (r *TenantRepository) InsertTenantWithAdmin(tenant models.Tenant, admin models.User) (models.Tenant, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tenant).Error; err != nil {
+			return err
+		}
+
+		admin.TenantID = tenant.ID
+		return tx.Create(&admin).Error
+	})
+	if err != nil {
+		r.logger.Error("Unable to insert tenant into the database. ERR: ", err.Error())
+		return tenant, errors.New("unable to insert object")
+	}
+
+	return tenant, nil
+}
+
+// SelectTenantByName returns a tenant, or nil if there is no such tenant.
+func (r *TenantRepository) SelectTenantByName(name string) (*models.Tenant, error) {
+	var tenant models.Tenant
+
+	err := r.DB.Where("name = ?", name).First(&tenant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve tenant. ERR: ", err.Error())
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+func (r *TenantRepository) SelectTenants() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+
+	if err := r.DB.Order("id asc").Find(&tenants).Error; err != nil {
+		r.logger.Error("Failed to retrieve tenants. ERR: ", err.Error())
+		return tenants, err
+	}
+
+	return tenants, nil
+}
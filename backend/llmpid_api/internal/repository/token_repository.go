@@ -11,24 +11,29 @@ import (
 )
 
 type TokenRepository struct {
-	serverSecretKey string
-	DB              *gorm.DB
-	logger          *logrus.Logger
+	SigningKeys *SigningKeyRepository
+	DB          *gorm.DB
+	logger      *logrus.Logger
 }
 
-func NewTokenRepository(serverSecretKey string, db *gorm.DB, logger *logrus.Logger) *TokenRepository {
-	return &TokenRepository{serverSecretKey: serverSecretKey, DB: db, logger: logger}
+func NewTokenRepository(signingKeys *SigningKeyRepository, db *gorm.DB, logger *logrus.Logger) *TokenRepository {
+	return &TokenRepository{SigningKeys: signingKeys, DB: db, logger: logger}
 }
 
 func (r *TokenRepository) ExtractAndValidateJWT(tokenString string) (*models.AccessTokenClaims, error) {
 	// Parse and verify the JWT token
 	token, err := jwt.ParseWithClaims(tokenString, &models.AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is HMAC and using HS256
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Look up the key named in the "kid" header and ensure the token was signed with its algorithm
+		kid, _ := token.Header["kid"].(string)
+		key, ok := r.SigningKeys.VerificationKey(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(r.serverSecretKey), nil
-	})
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{SigningAlgorithmEdDSA, SigningAlgorithmRS256}))
 
 	// Handle parsing or verification errors
 	if err != nil {
@@ -56,7 +61,7 @@ func (r *TokenRepository) ExtractAndValidateJWT(tokenString string) (*models.Acc
 	return claims, nil
 }
 
-func (r *TokenRepository) GenerateJWT(username string, sub string, expiration int64, role string, sessionSlug string) (string, models.AccessTokenClaims, error) {
+func (r *TokenRepository) GenerateJWT(username string, sub string, expiration int64, role string, tenantID uint, sessionSlug string) (string, models.AccessTokenClaims, error) {
 	now := time.Now()
 
 	accessClaims := models.AccessTokenClaims{
@@ -66,6 +71,7 @@ func (r *TokenRepository) GenerateJWT(username string, sub string, expiration in
 			"role":     role,
 		},
 		SessionID: sessionSlug,
+		TenantID:  tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute * time.Duration(expiration))),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -74,8 +80,15 @@ func (r *TokenRepository) GenerateJWT(username string, sub string, expiration in
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	tokenString, err := token.SignedString([]byte(r.serverSecretKey))
+	key, err := r.SigningKeys.activeKey()
+	if err != nil {
+		r.logger.Error("Unable to sign user token. ERR: ", err)
+		return "", accessClaims, errors.New("unable to create token")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), accessClaims)
+	token.Header["kid"] = key.Kid
+	tokenString, err := token.SignedString(key.privateKey)
 	if err != nil {
 		r.logger.Error("Unable to sign user token. ERR: ", err)
 		return "", accessClaims, errors.New("unable to create token")
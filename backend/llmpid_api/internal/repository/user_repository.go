@@ -5,9 +5,15 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"llm-promp-inj.api/internal/models"
 )
 
+// ErrLastAdmin is returned when a change would leave no enabled admin account.
+var ErrLastAdmin = errors.New("at least one enabled admin account must remain")
+
+var errUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
 	DB     *gorm.DB
 	logger *logrus.Logger
@@ -17,8 +23,8 @@ func NewUserRepository(db *gorm.DB, logger *logrus.Logger) *UserRepository {
 	return &UserRepository{DB: db, logger: logger}
 }
 
-func (r *UserRepository) InsertUser(username string, passwordHash string, role string) (models.User, error) {
-	user := models.User{Username: username, PasswordHash: passwordHash, Role: role}
+func (r *UserRepository) InsertUser(tenantID uint, username string, passwordHash string, role string) (models.User, error) {
+	user := models.User{TenantID: tenantID, Username: username, PasswordHash: passwordHash, Role: role}
 
 	err := r.DB.Create(&user).Error
 	if err != nil {
@@ -29,8 +35,8 @@ func (r *UserRepository) InsertUser(username string, passwordHash string, role s
 	return user, nil
 }
 
-func (r *UserRepository) UpdatePasswordHashByUserID(id uint, passwordHash string) error {
-	err := r.DB.Model(&models.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+func (r *UserRepository) UpdatePasswordHashByUserID(tenantID uint, id uint, passwordHash string) error {
+	err := r.DB.Model(&models.User{}).Scopes(tenantScope(tenantID)).Where("id = ?", id).Update("password_hash", passwordHash).Error
 	if err != nil {
 		r.logger.Error("Unable to update user's password hash. ERR: ", err.Error())
 		return errors.New("unalbe to update password")
@@ -39,9 +45,9 @@ func (r *UserRepository) UpdatePasswordHashByUserID(id uint, passwordHash string
 	return nil
 }
 
-func (r *UserRepository) UpdateUsername(oldUsername string, newUsername string) (models.User, error) {
+func (r *UserRepository) UpdateUsername(tenantID uint, oldUsername string, newUsername string) (models.User, error) {
 	var updatedUser models.User
-	updateEvent := r.DB.Model(&models.User{}).Where("username = ?", oldUsername).Update("username", newUsername)
+	updateEvent := r.DB.Model(&models.User{}).Scopes(tenantScope(tenantID)).Where("username = ?", oldUsername).Update("username", newUsername)
 
 	if updateEvent.Error != nil {
 		r.logger.Error("Unable to update user's username. ERR: ", updateEvent.Error.Error())
@@ -51,18 +57,32 @@ func (r *UserRepository) UpdateUsername(oldUsername string, newUsername string)
 	updateEvent.Scan(updatedUser)
 	return updatedUser, nil
 }
+
+// SelectUserByUsername finds a user of any tenant, since usernames are unique across tenants. It is meant for authentication,
+// while users are looked up on behalf of other users with SelectTenantUserByUsername.
 func (r *UserRepository) SelectUserByUsername(username string) (models.User, error) {
 	var foundUser models.User
-	if err := r.DB.Where("username = ?", username).First(&foundUser).Error; err != nil {
+	if err := r.DB.Scopes(allTenants).Where("username = ?", username).First(&foundUser).Error; err != nil {
 		return foundUser, err
 	}
 
 	return foundUser, nil
 }
-func (r *UserRepository) SelectUserByRole(role string) ([]models.User, error) {
+
+// SelectTenantUserByUsername finds a user of the tenant.
+func (r *UserRepository) SelectTenantUserByUsername(tenantID uint, username string) (models.User, error) {
+	var foundUser models.User
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("username = ?", username).First(&foundUser).Error; err != nil {
+		return foundUser, err
+	}
+
+	return foundUser, nil
+}
+
+func (r *UserRepository) SelectUserByRole(tenantID uint, role string) ([]models.User, error) {
 	var users []models.User
 
-	if err := r.DB.Where("role = ?", role).Find(&users).Error; err != nil {
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("role = ?", role).Find(&users).Error; err != nil {
 		r.logger.Error("Failed to retrieve user by role. ERR: ", err.Error())
 		return users, err
 	}
@@ -71,11 +91,118 @@ func (r *UserRepository) SelectUserByRole(role string) ([]models.User, error) {
 
 }
 
-func (r *UserRepository) DeleteByUsername(username string) error {
-	if err := r.DB.Where("username = ?", username).Delete(&models.User{}).Error; err != nil {
+func (r *UserRepository) DeleteByUsername(tenantID uint, username string) error {
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("username = ?", username).Delete(&models.User{}).Error; err != nil {
 		r.logger.Error("Failed to delete user from database. ERR: ", err.Error())
 		return errors.New("unable to delete object")
 	}
 
 	return nil
 }
+
+// DeleteExternalSystem removes an external system along with its classification policy and webhook in a single transaction,
+// so they are not inherited by a future system with the same name.
+func (r *UserRepository) DeleteExternalSystem(tenantID uint, username string) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(tenantScope(tenantID)).Where("source_name = ?", username).Delete(&models.ClassificationPolicy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(tenantScope(tenantID)).Where("source_name = ?", username).Delete(&models.Webhook{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Scopes(tenantScope(tenantID)).Where("username = ?", username).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		r.logger.Error("Failed to delete external system from database. ERR: ", err.Error())
+		return errors.New("unable to delete object")
+	}
+
+	return nil
+}
+
+// SelectUsersExceptRole returns all users of the tenant without the given role, ordered by username.
+func (r *UserRepository) SelectUsersExceptRole(tenantID uint, role string) ([]models.User, error) {
+	var users []models.User
+
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("role <> ?", role).Order("username").Find(&users).Error; err != nil {
+		r.logger.Error("Failed to retrieve users. ERR: ", err.Error())
+		return users, errors.New("unable to retrieve users")
+	}
+
+	return users, nil
+}
+
+// UpdateRoleByUsername changes the role of a user of the tenant unless no enabled admin would remain in the tenant.
+func (r *UserRepository) UpdateRoleByUsername(tenantID uint, username string, role string) error {
+	return r.keepingAdmin(tenantID, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.User{}).Where("username = ?", username).Update("role", role)
+	})
+}
+
+// UpdateDisabledByUsername disables or enables a user of the tenant unless no enabled admin would remain in the tenant.
+func (r *UserRepository) UpdateDisabledByUsername(tenantID uint, username string, disabled bool) error {
+	return r.keepingAdmin(tenantID, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.User{}).Where("username = ?", username).Update("disabled", disabled)
+	})
+}
+
+// DeleteUserByUsername deletes a user of the tenant unless no enabled admin would remain in the tenant.
+func (r *UserRepository) DeleteUserByUsername(tenantID uint, username string) error {
+	return r.keepingAdmin(tenantID, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("username = ?", username).Delete(&models.User{})
+	})
+}
+
+// keepingAdmin applies a change to a single user of the tenant and rolls it back if it left the tenant without an enabled admin account.
+// The enabled admins are locked during the change, so concurrent changes cannot remove the last admins together.
+func (r *UserRepository) keepingAdmin(tenantID uint, change func(tx *gorm.DB) *gorm.DB) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var adminIDs []uint
+		err := tx.Model(&models.User{}).Scopes(tenantScope(tenantID)).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ? AND disabled = ?", "admin", false).
+			Pluck("id", &adminIDs).Error
+		if err != nil {
+			return err
+		}
+
+		result := change(tx.Scopes(tenantScope(tenantID)))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUserNotFound
+		}
+
+		var admins int64
+		if err := tx.Model(&models.User{}).Scopes(tenantScope(tenantID)).Where("role = ? AND disabled = ?", "admin", false).Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins == 0 {
+			return ErrLastAdmin
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrLastAdmin) || errors.Is(err, errUserNotFound) {
+		return err
+	}
+	if err != nil {
+		r.logger.Error("Unable to update user. ERR: ", err.Error())
+		return errors.New("unable to update user")
+	}
+
+	return nil
+}
+
+// CountUsersByRole returns the number of users of all tenants the role is assigned to, since roles are shared by all tenants.
+func (r *UserRepository) CountUsersByRole(role string) (int64, error) {
+	var count int64
+
+	if err := r.DB.Model(&models.User{}).Scopes(allTenants).Where("role = ?", role).Count(&count).Error; err != nil {
+		r.logger.Error("Failed to count users by role. ERR: ", err.Error())
+		return 0, errors.New("unable to count users")
+	}
+
+	return count, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"llm-promp-inj.api/internal/models"
+)
+
+type WebhookRepository struct {
+	DB     *gorm.DB
+	logger *logrus.Logger
+}
+
+func NewWebhookRepository(db *gorm.DB, logger *logrus.Logger) *WebhookRepository {
+	return &WebhookRepository{DB: db, logger: logger}
+}
+
+// UpsertWebhook registers the webhook of an external system or replaces the existing one of the same tenant.
+func (r *WebhookRepository) UpsertWebhook(webhook models.Webhook) (models.Webhook, error) {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_name"}},
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "webhooks.tenant_id", Value: webhook.TenantID}}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "secret", "updated_at"}),
+	}).Create(&webhook).Error
+	if err != nil {
+		r.logger.Error("Unable to insert webhook into the database. ERR: ", err.Error())
+		return webhook, errors.New("unable to insert object")
+	}
+
+	return webhook, nil
+}
+
+// SelectWebhookBySourceName returns the webhook of an external system of the tenant, or nil if it has none.
+func (r *WebhookRepository) SelectWebhookBySourceName(tenantID uint, sourceName string) (*models.Webhook, error) {
+	var webhook models.Webhook
+
+	err := r.DB.Scopes(tenantScope(tenantID)).Where("source_name = ?", sourceName).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to retrieve webhook. ERR: ", err.Error())
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) UpdateSourceName(tenantID uint, oldSourceName string, newSourceName string) error {
+	err := r.DB.Model(&models.Webhook{}).Scopes(tenantScope(tenantID)).Where("source_name = ?", oldSourceName).Update("source_name", newSourceName).Error
+	if err != nil {
+		r.logger.Error("Unable to update the source name of a webhook. ERR: ", err.Error())
+		return errors.New("unable to update webhook")
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) DeleteBySourceName(tenantID uint, sourceName string) error {
+	if err := r.DB.Scopes(tenantScope(tenantID)).Where("source_name = ?", sourceName).Delete(&models.Webhook{}).Error; err != nil {
+		r.logger.Error("Failed to delete webhook from database. ERR: ", err.Error())
+		return errors.New("unable to delete object")
+	}
+
+	return nil
+}
@@ -34,7 +34,8 @@ func (s *AuthenticationService) Authenticate(username string, password string, s
 	if err != nil {
 		return "", err
 	}
-	if !isValidPass {
+	// Disabled users are rejected like wrong credentials.
+	if !isValidPass || user.Disabled {
 		return "", nil
 	}
 
@@ -49,6 +50,7 @@ func (s *AuthenticationService) Authenticate(username string, password string, s
 		tokenSub,
 		sessionLength,
 		user.Role,
+		user.TenantID,
 		sessioID)
 	if err != nil {
 		return "", err
@@ -56,7 +58,7 @@ func (s *AuthenticationService) Authenticate(username string, password string, s
 
 	// Create session for the user.
 	// It can be revoked at any time - all tokens containing the session ID (sessionSlug) will be invalidated.
-	err = s.SessionRepo.CreateSession(sessioID, claims.Sub, claims.ExpiresAt.Unix())
+	err = s.SessionRepo.CreateSession(sessioID, claims.Sub, claims.TenantID, claims.ExpiresAt.Unix())
 	if err != nil {
 		return "", err
 	}
@@ -87,7 +89,7 @@ func (s *AuthenticationService) ChangePassword(username string, oldPassword stri
 	}
 
 	// Update the user's database entry with the new password hash.
-	err = s.UserRepo.UpdatePasswordHashByUserID(user.ID, newHash)
+	err = s.UserRepo.UpdatePasswordHashByUserID(user.TenantID, user.ID, newHash)
 	if err != nil {
 		return "", err
 	}
@@ -107,8 +109,8 @@ func (s *AuthenticationService) ChangePassword(username string, oldPassword stri
 	return newAccessToken, nil
 }
 
-func (s *AuthenticationService) IsValidSession(sessioID string, sub string) bool {
-	return s.SessionRepo.IsValidSession(sessioID, sub)
+func (s *AuthenticationService) IsValidSession(sessioID string, sub string, tenantID uint) bool {
+	return s.SessionRepo.IsValidSession(sessioID, sub, tenantID)
 }
 
 func (s *AuthenticationService) RevokeSession(tokenString string) error {
@@ -117,7 +119,7 @@ func (s *AuthenticationService) RevokeSession(tokenString string) error {
 		return err
 	}
 
-	s.SessionRepo.DeleteSessionBySID(tokenClaims.SessionID)
+	s.SessionRepo.DeleteSessionBySID(tokenClaims.TenantID, tokenClaims.SessionID)
 	return nil
 }
 
@@ -127,18 +129,19 @@ func (s *AuthenticationService) RevokeAllSessionsByToken(tokenString string) err
 		return err
 	}
 
-	s.SessionRepo.DeleteSessionBySub(tokenClaims.Sub)
+	s.SessionRepo.DeleteSessionBySub(tokenClaims.TenantID, tokenClaims.Sub)
 	return nil
 }
 
-func (s *AuthenticationService) RevokeAllSessionsByUsername(username string) error {
-	user, err := s.UserRepo.SelectUserByUsername(username)
+// RevokeAllSessionsByUsername revokes the sessions of a user of the tenant.
+func (s *AuthenticationService) RevokeAllSessionsByUsername(tenantID uint, username string) error {
+	user, err := s.UserRepo.SelectTenantUserByUsername(tenantID, username)
 	if err != nil {
 		return err
 	}
 
 	sub := s.CryptoRepo.GenerateJWTSubject(user.Username, user.ID)
 
-	s.SessionRepo.DeleteSessionBySub(sub)
+	s.SessionRepo.DeleteSessionBySub(tenantID, sub)
 	return nil
 }
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/repository"
+)
+
+// Backoff bounds of webhook delivery retries.
+const (
+	webhookBaseBackoff = 2 * time.Second
+	webhookMaxBackoff  = 5 * time.Minute
+)
+
+// webhookPollInterval is the interval in which due webhook deliveries are claimed, e.g. retries and deliveries of other replicas.
+const webhookPollInterval = 5 * time.Second
+
+// webhookLeaseMargin is added to the delivery timeout to get the time a claimed delivery is reserved for its worker.
+const webhookLeaseMargin = 30 * time.Second
+
+// Jobs are leased to the instance that processes them. The instance renews the leases of its jobs while it runs,
+// and unfinished jobs whose lease expired (e.g. of a replica that stopped) are claimed by any instance.
+const (
+	jobLeaseDuration      = 2 * time.Minute
+	jobLeaseRenewInterval = 30 * time.Second
+	jobPollInterval       = 30 * time.Second
+)
+
+// ClassificationJobService classifies texts asynchronously with a pool of workers
+// and delivers the results to the webhooks of the external systems that submitted them.
+// Webhook deliveries are stored with their jobs and processed by a separate pool of delivery workers.
+type ClassificationJobService struct {
+	ClssService   *ClassificationService
+	JobRepo       *repository.ClassificationJobRepository
+	WebhookRepo   *repository.WebhookRepository
+	cfg           config.JobsConfiguration
+	instanceID    string
+	queue         chan models.ClassificationJob
+	deliveries    chan models.ClassificationJob
+	deliveriesDue chan struct{}
+	httpClient    *http.Client
+	logger        *logrus.Logger
+}
+
+func NewClassificationJobService(clssService *ClassificationService, jobRepo *repository.ClassificationJobRepository, webhookRepo *repository.WebhookRepository, cfg config.JobsConfiguration, logger *logrus.Logger) *ClassificationJobService {
+	return &ClassificationJobService{
+		ClssService:   clssService,
+		JobRepo:       jobRepo,
+		WebhookRepo:   webhookRepo,
+		cfg:           cfg,
+		instanceID:    newInstanceID(),
+		queue:         make(chan models.ClassificationJob, cfg.QueueSize),
+		deliveries:    make(chan models.ClassificationJob),
+		deliveriesDue: make(chan struct{}, 1),
+		httpClient:    newWebhookClient(time.Duration(cfg.WebhookTimeout)*time.Second, cfg.WebhookAllowedHosts),
+		logger:        logger,
+	}
+}
+
+// newInstanceID identifies the running instance of the API as the holder of job leases. A restarted instance gets a new ID,
+// so the jobs it held are only resumed once their lease has expired.
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "llmpid"
+	}
+
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+// Start launches the worker pools and the resumption of unfinished jobs whose lease has expired, e.g. because the API was restarted.
+// Pending webhook deliveries are picked up by the delivery workers, since their state is stored with the jobs.
+func (s *ClassificationJobService) Start() {
+	workers := s.cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	webhookWorkers := s.cfg.WebhookWorkers
+	if webhookWorkers <= 0 {
+		webhookWorkers = 1
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		go s.deliverWebhooks()
+	}
+	go s.dispatchWebhooks(webhookWorkers)
+
+	go s.renewJobLeases()
+	go s.resumeJobs()
+}
+
+// renewJobLeases keeps the jobs that are queued or processed by the instance reserved for it.
+func (s *ClassificationJobService) renewJobLeases() {
+	ticker := time.NewTicker(jobLeaseRenewInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.JobRepo.RenewJobLeases(s.instanceID, time.Now().Add(jobLeaseDuration)); err != nil {
+			s.logger.Error("Unable to renew the leases of classification jobs. ERR: ", err)
+		}
+	}
+}
+
+// resumeJobs claims the unfinished jobs whose lease has expired and queues them for the workers.
+// At most as many jobs are claimed as the queue can take, and the rest is left to other replicas.
+func (s *ClassificationJobService) resumeJobs() {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if free := cap(s.queue) - len(s.queue); free > 0 {
+			now := time.Now()
+			jobs, err := s.JobRepo.ClaimUnfinishedJobs(s.instanceID, now, now.Add(jobLeaseDuration), free)
+			if err != nil {
+				s.logger.Error("Unable to resume unfinished classification jobs. ERR: ", err)
+			}
+			for _, job := range jobs {
+				s.queue <- job
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// Submit stores a new classification job of a source of the tenant and queues it for the workers.
+func (s *ClassificationJobService) Submit(classificationRequest dto.ClassificationRequest, tenantID uint, sourceName string) (models.ClassificationJob, error) {
+	if classificationRequest.Text == "" {
+		return models.ClassificationJob{}, errors.New("text is required")
+	}
+	if err := validateClassificationContext(classificationRequest.ClassificationContext); err != nil {
+		return models.ClassificationJob{}, err
+	}
+
+	jobID, err := newCorrelationID()
+	if err != nil {
+		return models.ClassificationJob{}, err
+	}
+
+	leaseUntil := time.Now().Add(jobLeaseDuration)
+	job := models.ClassificationJob{
+		ID:          jobID,
+		TenantID:    tenantID,
+		SourceName:  sourceName,
+		RequestText: classificationRequest.Text,
+		Status:      models.JobStatusPending,
+		LockedBy:    s.instanceID,
+		LeaseUntil:  &leaseUntil,
+
+		ClassificationContext: classificationRequest.ClassificationContext,
+	}
+	if err := s.JobRepo.InsertJob(&job); err != nil {
+		return models.ClassificationJob{}, err
+	}
+
+	select {
+	case s.queue <- job:
+	default:
+		if err := s.JobRepo.UpdateJobStatus(job.TenantID, job.ID, models.JobStatusFailed, nil, "job queue is full"); err != nil {
+			s.logger.Error("Unable to mark rejected classification job ", job.ID, " as failed. ERR: ", err)
+		}
+		return models.ClassificationJob{}, errors.New("job queue is full")
+	}
+
+	return job, nil
+}
+
+// Get returns a job of the tenant along with its result. Jobs of other sources are only visible if global is set (e.g. for admins).
+func (s *ClassificationJobService) Get(tenantID uint, id string, sourceName string, global bool) (dto.ClassificationJobResponse, error) {
+	job, err := s.JobRepo.SelectJobByID(tenantID, id)
+	if err != nil || (!global && job.SourceName != sourceName) {
+		return dto.ClassificationJobResponse{}, errors.New("job not found")
+	}
+
+	jobResponse := dto.ClassificationJobResponse{ClassificationJob: job}
+	if job.LogID != nil {
+		clssLog, err := s.ClssService.ClassificationLogsRepo.SelectClassificationLogByID(*job.LogID, dto.SourceClassificationLogs(job.TenantID, job.SourceName))
+		if err != nil {
+			return jobResponse, err
+		}
+		jobResponse.Result = &clssLog
+	}
+
+	return jobResponse, nil
+}
+
+func (s *ClassificationJobService) work() {
+	for job := range s.queue {
+		s.process(job)
+	}
+}
+
+// process classifies the text of a job and stores the outcome along with a webhook delivery, if the source has a webhook.
+// A job whose lease was taken over by another instance while it was queued is left to that instance.
+func (s *ClassificationJobService) process(job models.ClassificationJob) {
+	held, err := s.JobRepo.StartJob(job.TenantID, job.ID, s.instanceID, time.Now().Add(jobLeaseDuration))
+	if err != nil {
+		s.logger.Error("Unable to start classification job ", job.ID, ". ERR: ", err)
+		return
+	}
+	if !held {
+		s.logger.Warn("Classification job ", job.ID, " was taken over by another instance.")
+		return
+	}
+
+	status := models.JobStatusCompleted
+	var logID *uint
+	var errMessage string
+
+	clssResponse, err := s.ClssService.classifyAndLog(context.Background(), dto.ClassificationRequest{Text: job.RequestText, ClassificationContext: job.ClassificationContext}, job.TenantID, job.SourceName, job.ID)
+	if err != nil {
+		status = models.JobStatusFailed
+		errMessage = err.Error()
+	} else {
+		logID = &clssResponse.ID
+	}
+
+	var webhookDueAt *time.Time
+	webhook, err := s.WebhookRepo.SelectWebhookBySourceName(job.TenantID, job.SourceName)
+	if err != nil {
+		s.logger.Error("Unable to look up the webhook of classification job ", job.ID, ". The result is not delivered. ERR: ", err)
+	} else if webhook != nil {
+		now := time.Now()
+		webhookDueAt = &now
+	}
+
+	if err := s.JobRepo.FinishJob(job.TenantID, job.ID, status, logID, errMessage, webhookDueAt); err != nil {
+		s.logger.Error("Unable to store the outcome of classification job ", job.ID, ". ERR: ", err)
+		return
+	}
+
+	if webhookDueAt != nil {
+		s.notifyDeliveriesDue()
+	}
+}
+
+// notifyDeliveriesDue wakes up the webhook dispatcher without waiting for its next poll.
+func (s *ClassificationJobService) notifyDeliveriesDue() {
+	select {
+	case s.deliveriesDue <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchWebhooks claims the due webhook deliveries and hands them to the delivery workers. Claiming at most one delivery
+// per worker at a time bounds the number of concurrent deliveries and leaves the rest to other replicas.
+func (s *ClassificationJobService) dispatchWebhooks(batchSize int) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	lease := time.Duration(s.cfg.WebhookTimeout)*time.Second + webhookLeaseMargin
+	for {
+		for {
+			now := time.Now()
+			jobs, err := s.JobRepo.ClaimDueWebhookDeliveries(now, now.Add(lease), batchSize)
+			if err != nil {
+				break
+			}
+			for _, job := range jobs {
+				s.deliveries <- job
+			}
+			if len(jobs) < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.deliveriesDue:
+		}
+	}
+}
+
+func (s *ClassificationJobService) deliverWebhooks() {
+	for job := range s.deliveries {
+		s.deliver(job)
+	}
+}
+
+// deliver makes a single delivery attempt of the result of a job. Failed attempts are rescheduled with exponential backoff and jitter
+// until the maximum number of retries is reached.
+func (s *ClassificationJobService) deliver(job models.ClassificationJob) {
+	attempt := job.WebhookAttempts + 1
+	maxAttempts := s.cfg.WebhookMaxRetries + 1
+
+	status := models.WebhookStatusDelivered
+	var nextAttemptAt *time.Time
+
+	err := s.deliverJobResult(job)
+	if err != nil {
+		s.logger.Warn("Webhook delivery of job ", job.ID, " failed on attempt ", attempt, ". ERR: ", err)
+
+		status = models.WebhookStatusFailed
+		if attempt < maxAttempts {
+			next := time.Now().Add(webhookBackoff(attempt))
+			status, nextAttemptAt = models.WebhookStatusPending, &next
+		}
+	}
+
+	if err := s.JobRepo.UpdateWebhookStatus(job.TenantID, job.ID, status, attempt, nextAttemptAt); err != nil {
+		s.logger.Error("Unable to record the webhook delivery of job ", job.ID, ". It is attempted again once its lease expires. ERR: ", err)
+	}
+}
+
+// deliverJobResult sends the outcome of a job to the current webhook of its source.
+func (s *ClassificationJobService) deliverJobResult(job models.ClassificationJob) error {
+	webhook, err := s.WebhookRepo.SelectWebhookBySourceName(job.TenantID, job.SourceName)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return errors.New("webhook was removed")
+	}
+
+	payload := dto.WebhookPayload{JobID: job.ID, Status: job.Status, Error: job.Error}
+	if job.LogID != nil {
+		clssLog, err := s.ClssService.ClassificationLogsRepo.SelectClassificationLogByID(*job.LogID, dto.SourceClassificationLogs(job.TenantID, job.SourceName))
+		if err != nil {
+			return err
+		}
+		payload.Result = &clssLog
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return s.send(*webhook, body)
+}
+
+// send performs a single webhook delivery. The body is signed with HMAC-SHA256 over "{timestamp}.{body}".
+func (s *ClassificationJobService) send(webhook models.Webhook, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-LLMPID-Timestamp", timestamp)
+	req.Header.Set("X-LLMPID-Signature", "sha256="+signature)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// webhookBackoff returns the exponential delay before the next delivery attempt with up to 50% of random jitter.
+func webhookBackoff(attempt int) time.Duration {
+	backoff := webhookBaseBackoff << (attempt - 1)
+	if backoff > webhookMaxBackoff || backoff <= 0 {
+		backoff = webhookMaxBackoff
+	}
+
+	return backoff + rand.N(backoff/2+1)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// maxLogsPageLimit is the maximum number of classification logs returned at once.
+const maxLogsPageLimit = 100
+
+// ErrInvalidCursor is returned when a pagination cursor was not issued by the API.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrUnsupportedCursorOrder is returned when logs paginated by cursors are requested in another order than by their creation time.
+var ErrUnsupportedCursorOrder = errors.New("logs paginated by cursors can only be ordered by created_at")
+
+// GetClassificationLogsByCursor returns the logs matching the filter that follow the position of the cursor, or the first logs if there is no cursor.
+// Logs are ordered by their creation time and ID, which keeps the query fast on large tables and the pages stable while new logs are inserted.
+// The order of a cursor is fixed when the first page is requested, so sortBy only applies without a cursor.
+// Ordering by another column than created_at is rejected rather than ignored.
+func (s *ClassificationService) GetClassificationLogsByCursor(cursor string, limit int, orderBy string, sortBy string, filter dto.ClassificationLogFilter, claims *models.AccessTokenClaims) (dto.ClassificationLogCursorPage, error) {
+	if orderBy != "" && orderBy != "created_at" {
+		return dto.ClassificationLogCursorPage{}, ErrUnsupportedCursorOrder
+	}
+
+	position := dto.ClassificationLogCursor{Desc: sortBy != "asc"}
+	var after *dto.ClassificationLogCursor
+
+	if cursor != "" {
+		decoded, err := decodeLogCursor(cursor)
+		if err != nil {
+			return dto.ClassificationLogCursorPage{}, err
+		}
+		position = decoded
+		after = &position
+	}
+
+	if limit < 1 {
+		limit = 15
+	}
+	limit = min(limit, maxLogsPageLimit)
+
+	// Logs preceding the position are scanned in the reverse order and reversed afterwards.
+	// One more log than requested is fetched to find out whether there are further logs.
+	ascending := position.Desc == position.Backward
+	clssLogs, err := s.ClassificationLogsRepo.SelectClassificationLogsByKeyset(after, ascending, limit+1, filter, logScope(claims))
+	if err != nil {
+		return dto.ClassificationLogCursorPage{}, err
+	}
+
+	more := len(clssLogs) > limit
+	if more {
+		clssLogs = clssLogs[:limit]
+	}
+	if position.Backward {
+		slices.Reverse(clssLogs)
+	}
+
+	page := dto.ClassificationLogCursorPage{Data: clssLogs, Limit: limit}
+	if len(clssLogs) == 0 {
+		return page, nil
+	}
+
+	// The log at the position of the cursor lies in the opposite direction of the scan.
+	hasNext, hasPrev := more, after != nil
+	if position.Backward {
+		hasNext, hasPrev = after != nil, more
+	}
+	if hasNext {
+		page.NextCursor = encodeLogCursor(clssLogs[len(clssLogs)-1], position.Desc, false)
+	}
+	if hasPrev {
+		page.PrevCursor = encodeLogCursor(clssLogs[0], position.Desc, true)
+	}
+
+	return page, nil
+}
+
+func encodeLogCursor(clssLog models.ClassificationLog, desc bool, backward bool) string {
+	payload, _ := json.Marshal(dto.ClassificationLogCursor{CreatedAt: clssLog.CreatedAt, ID: clssLog.ID, Desc: desc, Backward: backward})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeLogCursor(cursor string) (dto.ClassificationLogCursor, error) {
+	var position dto.ClassificationLogCursor
+
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return position, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &position); err != nil || position.ID == 0 {
+		return position, ErrInvalidCursor
+	}
+
+	return position, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"llm-promp-inj.api/internal/models"
+)
+
+func TestLogCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 12, 30, 15, 123456000, time.UTC)
+
+	tests := []struct {
+		name     string
+		id       uint
+		desc     bool
+		backward bool
+	}{
+		{name: "ascending", id: 1},
+		{name: "descending", id: 4711, desc: true},
+		{name: "descending backward", id: 4711, desc: true, backward: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clssLog := models.ClassificationLog{ID: tt.id, CreatedAt: createdAt}
+
+			position, err := decodeLogCursor(encodeLogCursor(clssLog, tt.desc, tt.backward))
+			if err != nil {
+				t.Fatalf("decodeLogCursor() error = %v", err)
+			}
+			if position.ID != tt.id || !position.CreatedAt.Equal(createdAt) || position.Desc != tt.desc || position.Backward != tt.backward {
+				t.Errorf("decodeLogCursor() = %+v, want ID %d at %v (desc %t, backward %t)", position, tt.id, createdAt, tt.desc, tt.backward)
+			}
+		})
+	}
+}
+
+func TestDecodeLogCursorRejectsInvalidCursors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "!!!"},
+		{name: "not JSON", cursor: base64.RawURLEncoding.EncodeToString([]byte("not json"))},
+		{name: "missing ID", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2025-03-01T12:00:00Z"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeLogCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeLogCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// Formats of classification log exports.
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatParquet = "parquet"
+)
+
+// Treatments of the request text in classification log exports.
+const (
+	ExportTextInclude = "include"
+	ExportTextRedact  = "redact"
+	ExportTextHash    = "hash"
+)
+
+// exportRowGroupSize is the number of rows buffered for every row group of a Parquet export.
+const exportRowGroupSize = 10000
+
+// ErrInvalidExport is returned when the format or the request text treatment of an export is unknown.
+var ErrInvalidExport = errors.New("invalid export format or request text treatment")
+
+// logExportWriter encodes classification log rows in one of the export formats.
+type logExportWriter interface {
+	Write(row dto.ClassificationLogExportRow) error
+	Close() error
+}
+
+// ExportClassificationLogs writes all logs matching the filter to w in the given format, in the order of their creation.
+// The logs are streamed from the database row by row, so exports of any size use constant memory.
+// Nothing is written to w if the options are invalid.
+func (s *ClassificationService) ExportClassificationLogs(ctx context.Context, w io.Writer, format string, textMode string, filter dto.ClassificationLogFilter, claims *models.AccessTokenClaims) error {
+	switch textMode {
+	case "":
+		textMode = ExportTextInclude
+	case ExportTextInclude, ExportTextRedact, ExportTextHash:
+	default:
+		return ErrInvalidExport
+	}
+
+	var writer logExportWriter
+	switch format {
+	case ExportFormatCSV:
+		writer = &csvExportWriter{writer: csv.NewWriter(w)}
+	case ExportFormatJSONL:
+		writer = &jsonlExportWriter{encoder: json.NewEncoder(w)}
+	case ExportFormatParquet:
+		writer = &parquetExportWriter{writer: parquet.NewGenericWriter[dto.ClassificationLogExportRow](w, parquet.MaxRowsPerRowGroup(exportRowGroupSize))}
+	default:
+		return ErrInvalidExport
+	}
+
+	err := s.ClassificationLogsRepo.StreamClassificationLogs(ctx, filter, logScope(claims), func(clssLog models.ClassificationLog) error {
+		return writer.Write(exportRow(clssLog, textMode))
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// exportRow flattens the classification log and applies the request text treatment.
+func exportRow(clssLog models.ClassificationLog, textMode string) dto.ClassificationLogExportRow {
+	requestText := clssLog.RequestText
+	switch textMode {
+	case ExportTextRedact:
+		requestText = ""
+	case ExportTextHash:
+		hash := sha256.Sum256([]byte(clssLog.RequestText))
+		requestText = hex.EncodeToString(hash[:])
+	}
+
+	row := dto.ClassificationLogExportRow{
+		ID:             clssLog.ID,
+		CreatedAt:      clssLog.CreatedAt,
+		SourceName:     clssLog.SourceName,
+		RequestText:    requestText,
+		Result:         clssLog.Result,
+		Score:          clssLog.Score,
+		Threshold:      clssLog.Threshold,
+		ModelVersion:   clssLog.ModelVersion,
+		Action:         clssLog.Action,
+		Role:           clssLog.Role,
+		ConversationID: clssLog.ConversationID,
+		SourceRef:      clssLog.SourceRef,
+		Tags:           clssLog.Tags,
+		Transformation: clssLog.Transformation,
+		MatchedRules:   clssLog.MatchedRules,
+		ChunkCount:     clssLog.ChunkCount,
+		CacheHit:       clssLog.CacheHit,
+	}
+	if clssLog.Label != nil {
+		row.Label = *clssLog.Label
+	}
+
+	return row
+}
+
+// csvExportWriter writes a header followed by a line per row. List columns are encoded as JSON arrays.
+type csvExportWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+var csvExportHeader = []string{
+	"id", "created_at", "source_name", "request_text", "result", "score", "threshold", "model_version", "action",
+	"role", "conversation_id", "source_ref", "tags", "transformation", "matched_rules", "chunk_count", "cache_hit", "label",
+}
+
+func (c *csvExportWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+
+	return c.writer.Write(csvExportHeader)
+}
+
+func (c *csvExportWriter) Write(row dto.ClassificationLogExportRow) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	tags, err := models.StringList(row.Tags).Value()
+	if err != nil {
+		return err
+	}
+	matchedRules, err := models.StringList(row.MatchedRules).Value()
+	if err != nil {
+		return err
+	}
+
+	record := []string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		row.CreatedAt.Format(time.RFC3339Nano),
+		row.SourceName,
+		row.RequestText,
+		row.Result,
+		strconv.FormatFloat(row.Score, 'f', -1, 64),
+		strconv.FormatFloat(row.Threshold, 'f', -1, 64),
+		row.ModelVersion,
+		row.Action,
+		row.Role,
+		row.ConversationID,
+		row.SourceRef,
+		tags.(string),
+		row.Transformation,
+		matchedRules.(string),
+		strconv.Itoa(row.ChunkCount),
+		strconv.FormatBool(row.CacheHit),
+		row.Label,
+	}
+	for i, cell := range record {
+		record[i] = escapeCSVFormula(cell)
+	}
+
+	return c.writer.Write(record)
+}
+
+// escapeCSVFormula prefixes cells that spreadsheet applications would evaluate as formulas with an apostrophe,
+// so texts submitted for classification cannot run formulas on the machine of whoever opens the export.
+func escapeCSVFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+func (c *csvExportWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
+
+// jsonlExportWriter writes a JSON object per line.
+type jsonlExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlExportWriter) Write(row dto.ClassificationLogExportRow) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return nil
+}
+
+// parquetExportWriter writes the rows in row groups of exportRowGroupSize rows. The file is complete once the footer is written on Close.
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[dto.ClassificationLogExportRow]
+}
+
+func (p *parquetExportWriter) Write(row dto.ClassificationLogExportRow) error {
+	_, err := p.writer.Write([]dto.ClassificationLogExportRow{row})
+	return err
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{cell: "", want: ""},
+		{cell: "hello", want: "hello"},
+		{cell: "=1+1", want: "'=1+1"},
+		{cell: "+1", want: "'+1"},
+		{cell: "-5", want: "'-5"},
+		{cell: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{cell: "\tcell", want: "'\tcell"},
+		{cell: "\rcell", want: "'\rcell"},
+		{cell: " =1+1", want: " =1+1"},
+		{cell: "a=1", want: "a=1"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeCSVFormula(tt.cell); got != tt.want {
+			t.Errorf("escapeCSVFormula(%q) = %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// LabelClassificationLog records the ground truth label assigned to a classification log by the reviewer of the claims.
+func (s *ClassificationService) LabelClassificationLog(id uint, labelRequest dto.LabelRequest, claims *models.AccessTokenClaims) (models.ClassificationLog, error) {
+	switch labelRequest.Label {
+	case models.LabelInjection, models.LabelBenign:
+	default:
+		return models.ClassificationLog{}, errors.New("label must be either injection or benign")
+	}
+	if len([]rune(labelRequest.Note)) > 2000 {
+		return models.ClassificationLog{}, errors.New("note must not be longer than 2000 characters")
+	}
+
+	if claims == nil {
+		return models.ClassificationLog{}, errors.New("classification log not found")
+	}
+
+	scope := logScope(claims)
+	found, err := s.ClassificationLogsRepo.UpdateLabel(id, scope, labelRequest.Label, claims.Data["username"], labelRequest.Note)
+	if err != nil {
+		return models.ClassificationLog{}, err
+	}
+	if !found {
+		return models.ClassificationLog{}, errors.New("classification log not found")
+	}
+
+	return s.ClassificationLogsRepo.SelectClassificationLogByID(id, scope)
+}
+
+// GetReviewQueue returns up to limit unlabeled classification logs matching the filter, starting with the least confident verdicts.
+// A positive maxMargin only returns logs whose score is at most maxMargin away from their threshold.
+func (s *ClassificationService) GetReviewQueue(filter dto.ClassificationLogFilter, claims *models.AccessTokenClaims, maxMargin float64, limit int) ([]models.ClassificationLog, error) {
+	if limit < 1 {
+		limit = 15
+	}
+	limit = min(limit, maxLogsPageLimit)
+
+	return s.ClassificationLogsRepo.SelectReviewQueue(filter, logScope(claims), maxMargin, limit)
+}
+
+// GetLabelMetrics measures the verdicts of the labeled classifications of the tenant created in the date range against their labels,
+// overall, per external system and per model version.
+func (s *ClassificationService) GetLabelMetrics(tenantID uint, from time.Time, to time.Time) (dto.LabelMetricsReport, error) {
+	report := dto.LabelMetricsReport{}
+	scope := dto.TenantClassificationLogs(tenantID)
+	if !from.IsZero() {
+		report.From = &from
+	}
+	if !to.IsZero() {
+		report.To = &to
+	}
+
+	overall, err := s.ClassificationLogsRepo.SelectLabelMetrics(scope, "", from, to)
+	if err != nil {
+		return report, err
+	}
+	if len(overall) > 0 {
+		report.Overall = overall[0]
+	}
+
+	report.BySource, err = s.ClassificationLogsRepo.SelectLabelMetrics(scope, "source_name", from, to)
+	if err != nil {
+		return report, err
+	}
+
+	report.ByModelVersion, err = s.ClassificationLogsRepo.SelectLabelMetrics(scope, "model_version", from, to)
+	if err != nil {
+		return report, err
+	}
+
+	setPrecisionRecall(&report.Overall)
+	for i := range report.BySource {
+		setPrecisionRecall(&report.BySource[i])
+	}
+	for i := range report.ByModelVersion {
+		setPrecisionRecall(&report.ByModelVersion[i])
+	}
+
+	return report, nil
+}
+
+// setPrecisionRecall derives precision and recall from the confusion matrix. Both are 0 if they are undefined.
+func setPrecisionRecall(metrics *dto.LabelMetrics) {
+	if predicted := metrics.TruePositives + metrics.FalsePositives; predicted > 0 {
+		metrics.Precision = float64(metrics.TruePositives) / float64(predicted)
+	}
+	if actual := metrics.TruePositives + metrics.FalseNegatives; actual > 0 {
+		metrics.Recall = float64(metrics.TruePositives) / float64(actual)
+	}
+}
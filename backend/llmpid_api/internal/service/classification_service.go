@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
 	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/repository"
@@ -10,49 +17,514 @@ import (
 
 type ClassificationService struct {
 	ClassificationLogsRepo *repository.ClassificationLogsRepository
-	ClassificationRepo     *repository.InternalClassifierAPIRepository
+	ClassificationRepo     repository.Classifier
+	ShadowRepo             repository.Classifier // Optional candidate classifier that receives a copy of the traffic.
+	PolicyRepo             *repository.PolicyRepository
+	RuleRepo               *repository.RuleRepository
+	ConversationRepo       *repository.ConversationRiskRepository
+	cfg                    config.ClassifierConfiguration
+	shadowCfg              config.ShadowConfiguration
+	conversationCfg        config.ConversationConfiguration
+	shadowSlots            chan struct{}
+	logger                 *logrus.Logger
 }
 
-func NewClassificationService(logsRepo *repository.ClassificationLogsRepository, clsRepo *repository.InternalClassifierAPIRepository) *ClassificationService {
+func NewClassificationService(logsRepo *repository.ClassificationLogsRepository, clsRepo repository.Classifier, shadowRepo repository.Classifier, policyRepo *repository.PolicyRepository, ruleRepo *repository.RuleRepository, conversationRepo *repository.ConversationRiskRepository, cfg config.ClassifierConfiguration, shadowCfg config.ShadowConfiguration, conversationCfg config.ConversationConfiguration, logger *logrus.Logger) *ClassificationService {
 	return &ClassificationService{
 		ClassificationLogsRepo: logsRepo,
 		ClassificationRepo:     clsRepo,
+		ShadowRepo:             shadowRepo,
+		PolicyRepo:             policyRepo,
+		RuleRepo:               ruleRepo,
+		ConversationRepo:       conversationRepo,
+		cfg:                    cfg,
+		shadowCfg:              shadowCfg,
+		conversationCfg:        conversationCfg,
+		shadowSlots:            make(chan struct{}, max(shadowCfg.MaxConcurrency, 1)),
+		logger:                 logger,
 	}
 }
 
 // ClassifyText performs prompt injection classification for a privded string.
-// First, it sends the string for classification to an internal API, retrieves and logs the result into a database, and then returns it to the client service.
-func (s *ClassificationService) ClassifyText(ClassificationLog dto.ClassificationRequest, sourceName string) (models.ClassificationLog, error) {
-	// Send data for classification.
-	clssResult, err := s.ClassificationRepo.SendClassificationRequest(ClassificationLog)
+// First, it sends the string for classification to the configured classifier engine and applies the classification policy of the source.
+// Then it logs the result and the resulting action into a database, and returns it to the client service
+// along with the risk state of the conversation the text belongs to. The log belongs to the tenant of the source.
+func (s *ClassificationService) ClassifyText(ctx context.Context, ClassificationLog dto.ClassificationRequest, tenantID uint, sourceName string) (dto.ClassificationResponse, error) {
+	return s.classifyAndLog(ctx, ClassificationLog, tenantID, sourceName, "")
+}
+
+// classifyAndLog classifies the text and logs the result along with the ID correlating it to a stream or job, if any.
+func (s *ClassificationService) classifyAndLog(ctx context.Context, classificationRequest dto.ClassificationRequest, tenantID uint, sourceName string, correlationID string) (dto.ClassificationResponse, error) {
+	if err := validateClassificationContext(classificationRequest.ClassificationContext); err != nil {
+		return dto.ClassificationResponse{}, err
+	}
+
+	clssRequest, err := s.evaluate(ctx, s.ClassificationRepo, classificationRequest, tenantID, sourceName)
 	if err != nil {
-		return models.ClassificationLog{}, err
+		return dto.ClassificationResponse{}, err
 	}
+	clssRequest.CorrelationID = correlationID
+
+	// Make a DB entry for the classification log.
+	err = s.ClassificationLogsRepo.InsertClassificationLog(&clssRequest)
+	if err != nil {
+		return dto.ClassificationResponse{}, err
+	}
+	s.mirrorToShadow(clssRequest)
+
+	// The classification is returned even if the conversation risk could not be updated.
+	conversation, _ := s.trackConversation(clssRequest)
 
+	return dto.ClassificationResponse{ClassificationLog: clssRequest, Conversation: conversation}, nil
+}
+
+// evaluate classifies the text with the classifier and applies the classification policy of the source without logging the result.
+func (s *ClassificationService) evaluate(ctx context.Context, classifier repository.Classifier, classificationRequest dto.ClassificationRequest, tenantID uint, sourceName string) (models.ClassificationLog, error) {
 	if len(sourceName) <= 0 {
 		sourceName = "undefined"
 	}
 
-	// Create a classification log with the request and result and make a DB entry.
-	clssRequest := models.ClassificationLog{SourceName: sourceName, RequestText: ClassificationLog.Text, Result: clssResult}
-	err = s.ClassificationLogsRepo.InsertClassificationLog(clssRequest)
+	// Retrieve the thresholds configured for the source. Sources without a policy rely on the classifier's decision.
+	policy, err := s.PolicyRepo.SelectPolicyBySourceName(tenantID, sourceName)
 	if err != nil {
 		return models.ClassificationLog{}, err
 	}
 
-	return clssRequest, nil
+	variants := normalizeText(classificationRequest.Text, s.cfg.Normalization)
+
+	// Match the signatures of known injections against every variant of the text.
+	variantTexts := make([]string, len(variants))
+	for i, variant := range variants {
+		variantTexts[i] = variant.Text
+	}
+	matchedRules := s.RuleRepo.Match(variantTexts...)
+
+	verdict, err := s.classifyVariants(ctx, classifier, variants, tenantID)
+	switch {
+	case err == nil:
+	case len(matchedRules) > 0:
+		// The detection rules act as a fallback when the classifier is unavailable.
+		verdict = textVerdict{
+			ClassifierResult: dto.ClassifierResult{Result: "Normal", ModelVersion: models.ModelVersionRules},
+			Transformation:   TransformationOriginal,
+		}
+	case errors.Is(err, repository.ErrCircuitOpen):
+		verdict = s.failModeVerdict(policy)
+	default:
+		return models.ClassificationLog{}, err
+	}
+	// An ensemble with a "rules" member already weighed the rules in its vote, so they are only applied as a fallback.
+	if err != nil || !repository.AppliesRules(classifier) {
+		applyRules(&verdict, matchedRules)
+	}
+	// Rules with the block action block the text in any case, even if an ensemble weighed them in its vote.
+	blockedByRule := false
+	for _, rule := range matchedRules {
+		blockedByRule = blockedByRule || rule.Action == models.ActionBlock
+	}
+
+	// Create a classification log with the request and result.
+	clssLog := models.ClassificationLog{
+		TenantID:       tenantID,
+		SourceName:     sourceName,
+		RequestText:    classificationRequest.Text,
+		Result:         verdict.Result,
+		Score:          verdict.Score,
+		Threshold:      verdict.Threshold,
+		ModelVersion:   verdict.ModelVersion,
+		Transformation: verdict.Transformation,
+		ChunkCount:     verdict.ChunkCount,
+		MatchedRules:   verdict.MatchedRules,
+		CacheHit:       verdict.CacheHit,
+		MemberVerdicts: verdict.Members,
+
+		ClassificationContext: classificationRequest.ClassificationContext,
+	}
+
+	// Record which span of a chunked text triggered the verdict.
+	if verdict.TriggerSpan != nil {
+		clssLog.TriggerSpanStart = &verdict.TriggerSpan.Start
+		clssLog.TriggerSpanEnd = &verdict.TriggerSpan.End
+	}
+	applyPolicy(policy, &clssLog, blockedByRule)
+
+	return clssLog, nil
+}
+
+// failModeVerdict returns the verdict used while the classifier's circuit breaker is open.
+// The fail mode of the source's policy takes precedence over the configured default.
+func (s *ClassificationService) failModeVerdict(policy *models.ClassificationPolicy) textVerdict {
+	failMode := s.cfg.FailMode
+	if policy != nil && policy.FailMode != "" {
+		failMode = policy.FailMode
+	}
+
+	verdict := textVerdict{Transformation: TransformationOriginal}
+	if failMode == models.FailModeOpen {
+		verdict.ClassifierResult = dto.ClassifierResult{Result: "Normal", ModelVersion: models.ModelVersionFailOpen}
+	} else {
+		verdict.ClassifierResult = dto.ClassifierResult{Result: "Injection", Score: 1, ModelVersion: models.ModelVersionFailClosed}
+	}
+
+	return verdict
 }
 
-func (s *ClassificationService) GetClassificationRequestLogByID(id uint) (models.ClassificationLog, error) {
-	clssRequest, err := s.ClassificationLogsRepo.SelectClassificationLogByID(id)
+// textVerdict is the combined classifier outcome of all variants and chunks of a text.
+type textVerdict struct {
+	dto.ClassifierResult
+	Transformation string
+	ChunkCount     int
+	TriggerSpan    *textChunk
+	MatchedRules   models.StringList
+}
+
+// classifyVariants classifies the variants produced by the normalization pipeline, starting with the original text.
+// The first variant classified as an injection decides the verdict. If there is none, the variant with the highest score does.
+// The verdict is a cache hit only if every classified variant was served from the cache.
+// Variants are classified sequentially, so a text that is not an injection costs one classification per variant and chunk.
+func (s *ClassificationService) classifyVariants(ctx context.Context, classifier repository.Classifier, variants []textVariant, tenantID uint) (textVerdict, error) {
+	var verdict textVerdict
+	cacheHit := true
+
+	for i, variant := range variants {
+		variantVerdict, err := s.classifyChunks(ctx, classifier, variant.Text, tenantID)
+		if err != nil {
+			return textVerdict{}, err
+		}
+		variantVerdict.Transformation = variant.Transformation
+		// The offsets of a transformed variant do not match the request text, which is what the log stores.
+		if variant.Transformation != TransformationOriginal {
+			variantVerdict.TriggerSpan = nil
+		}
+		cacheHit = cacheHit && variantVerdict.CacheHit
+
+		if i == 0 || variantVerdict.Score > verdict.Score || variantVerdict.Result == "Injection" {
+			verdict = variantVerdict
+		}
+
+		// No need to classify further variants once one of them exposed an injection.
+		if verdict.Result == "Injection" {
+			break
+		}
+	}
+	verdict.CacheHit = cacheHit
+
+	return verdict, nil
+}
+
+// applyRules marks the verdict as an injection if any detection rule matched.
+// The score is raised to the highest score of the matched rules.
+func applyRules(verdict *textVerdict, matchedRules []models.DetectionRule) {
+	if len(matchedRules) == 0 {
+		return
+	}
+
+	var ruleScore float64
+	for _, rule := range matchedRules {
+		verdict.MatchedRules = append(verdict.MatchedRules, rule.ID)
+		ruleScore = max(ruleScore, rule.Score)
+	}
+
+	verdict.Result = "Injection"
+	verdict.Score = max(verdict.Score, ruleScore)
+
+	// Without a classifier result, the rules define from which score a text is an injection.
+	if verdict.Threshold == 0 {
+		verdict.Threshold = ruleScore
+	}
+}
+
+// classifyChunks splits long texts into overlapping chunks, since the classifier only considers the beginning of a text.
+// Then it classifies the chunks with a bounded number of concurrent classifier calls and aggregates their results.
+func (s *ClassificationService) classifyChunks(ctx context.Context, classifier repository.Classifier, text string, tenantID uint) (textVerdict, error) {
+	chunks := chunkText(text, s.cfg.ChunkSize, s.cfg.ChunkOverlap)
+
+	concurrency := s.cfg.ChunkConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// The remaining chunks are not classified once a chunk failed, since the text cannot be classified anyway.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunkResults := make([]dto.ClassifierResult, len(chunks))
+	chunkErrs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, chunk textChunk) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			chunkResults[i], chunkErrs[i] = classifier.Classify(ctx, dto.ClassificationRequest{Text: chunk.Text, TenantID: tenantID})
+			if chunkErrs[i] != nil {
+				cancel()
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// Report the error that caused the cancellation rather than the cancellation of the other chunks.
+	var firstErr error
+	for _, err := range chunkErrs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return textVerdict{}, firstErr
+	}
+
+	cacheHit := true
+	for _, chunkResult := range chunkResults {
+		cacheHit = cacheHit && chunkResult.CacheHit
+	}
+
+	clssResult, trigger := aggregateChunkResults(chunkResults, s.cfg.ChunkAggregation)
+	clssResult.CacheHit = cacheHit
+
+	verdict := textVerdict{ClassifierResult: clssResult, ChunkCount: len(chunks)}
+	if len(chunks) > 1 {
+		verdict.TriggerSpan = &chunks[trigger]
+	}
+
+	return verdict, nil
+}
+
+// mirrorToShadow classifies the text of a logged classification with the shadow classifier in the background
+// and stores the shadow verdict next to the primary result. Classifications are not mirrored while all shadow slots are busy,
+// so a slow shadow classifier never delays the live traffic.
+func (s *ClassificationService) mirrorToShadow(clssLog models.ClassificationLog) {
+	if s.ShadowRepo == nil || rand.Float64() >= s.shadowCfg.SampleRate {
+		return
+	}
+
+	select {
+	case s.shadowSlots <- struct{}{}:
+	default:
+		return
+	}
+
+	go func() {
+		defer func() { <-s.shadowSlots }()
+
+		// The shadow text runs through the same pipeline as the primary classification, including the detection rules
+		// and the policy of the source, so the shadow verdict is the verdict the caller would have received.
+		clssRequest := dto.ClassificationRequest{Text: clssLog.RequestText, ClassificationContext: clssLog.ClassificationContext}
+		shadowLog, err := s.evaluate(context.Background(), s.ShadowRepo, clssRequest, clssLog.TenantID, clssLog.SourceName)
+		if err != nil {
+			return
+		}
+
+		// Verdicts made without the shadow classifier say nothing about the candidate model.
+		switch shadowLog.ModelVersion {
+		case models.ModelVersionRules, models.ModelVersionFailOpen, models.ModelVersionFailClosed:
+			return
+		}
+
+		err = s.ClassificationLogsRepo.UpdateShadowResult(clssLog.TenantID, clssLog.ID, shadowLog.Result, shadowLog.Score, shadowLog.ModelVersion)
+		if err != nil {
+			s.logger.Error("Unable to store the shadow verdict of classification log ", clssLog.ID, ". ERR: ", err)
+		}
+	}()
+}
+
+// GetShadowReport compares the verdicts of the shadow models with the primary verdicts of the classifications of the tenant
+// created in the date range. It also returns up to samples of the latest classifications on which they disagreed.
+func (s *ClassificationService) GetShadowReport(tenantID uint, from time.Time, to time.Time, samples int) (dto.ShadowReport, error) {
+	report := dto.ShadowReport{}
+	if !from.IsZero() {
+		report.From = &from
+	}
+	if !to.IsZero() {
+		report.To = &to
+	}
+
+	modelReports, err := s.ClassificationLogsRepo.SelectShadowReport(dto.TenantClassificationLogs(tenantID), from, to)
+	if err != nil {
+		return report, err
+	}
+	for i := range modelReports {
+		if modelReports[i].Compared > 0 {
+			modelReports[i].DisagreementRate = float64(modelReports[i].Disagreements) / float64(modelReports[i].Compared)
+		}
+	}
+	report.Models = modelReports
+
+	report.Samples, err = s.ClassificationLogsRepo.SelectShadowDisagreements(dto.TenantClassificationLogs(tenantID), from, to, samples)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// ClassifyBatch classifies every item of a batch with a bounded number of concurrent classifier calls.
+// Each item is logged separately and a failed item is reported in its own result without failing the whole batch.
+func (s *ClassificationService) ClassifyBatch(ctx context.Context, items []dto.BatchClassificationItem, tenantID uint, sourceName string) ([]dto.BatchClassificationItemResult, error) {
+	if len(items) == 0 {
+		return nil, errors.New("batch does not contain any items")
+	}
+	if s.cfg.BatchMaxItems > 0 && len(items) > s.cfg.BatchMaxItems {
+		return nil, fmt.Errorf("batch exceeds the maximum of %d items", s.cfg.BatchMaxItems)
+	}
+
+	// Results are matched to the items by their IDs, so every ID may only appear once.
+	itemIDs := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if _, ok := itemIDs[item.ID]; ok {
+			return nil, fmt.Errorf("duplicate item id %q", item.ID)
+		}
+		itemIDs[item.ID] = struct{}{}
+	}
+
+	concurrency := s.cfg.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]dto.BatchClassificationItemResult, len(items))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, item dto.BatchClassificationItem) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			results[i] = dto.BatchClassificationItemResult{ID: item.ID}
+			if item.Text == "" {
+				results[i].Status = "Failed"
+				results[i].Error = "text is required"
+				return
+			}
+
+			clssResponse, err := s.ClassifyText(ctx, dto.ClassificationRequest{Text: item.Text, ClassificationContext: item.ClassificationContext}, tenantID, sourceName)
+			if err != nil {
+				results[i].Status = "Failed"
+				results[i].Error = err.Error()
+				return
+			}
+
+			results[i].Status = "Success"
+			results[i].Result = &clssResponse
+		}(i, item)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// applyPolicy labels the classification log and sets the action based on the score ranges of the policy.
+// Without a policy, injections are blocked and normal texts are allowed.
+// Scores raised by detection rules are subject to the thresholds as well, unless a matched rule has the block action.
+func applyPolicy(policy *models.ClassificationPolicy, clssLog *models.ClassificationLog, blockedByRule bool) {
+	if blockedByRule {
+		if policy != nil {
+			clssLog.Threshold = policy.Threshold
+		}
+		clssLog.Result = "Injection"
+		clssLog.Action = models.ActionBlock
+		return
+	}
+
+	if policy == nil {
+		clssLog.Action = models.ActionAllow
+		if clssLog.Result == "Injection" {
+			clssLog.Action = models.ActionBlock
+		}
+		return
+	}
+
+	clssLog.Threshold = policy.Threshold
+	clssLog.Result = "Normal"
+	if clssLog.Score >= policy.Threshold {
+		clssLog.Result = "Injection"
+	}
+
+	switch {
+	case clssLog.Score >= policy.BlockThreshold:
+		clssLog.Action = models.ActionBlock
+	case clssLog.Score >= policy.FlagThreshold:
+		clssLog.Action = models.ActionFlag
+	default:
+		clssLog.Action = models.ActionAllow
+	}
+}
+
+// validateClassificationContext assures that the optional context of a classification request is valid.
+func validateClassificationContext(clssContext models.ClassificationContext) error {
+	switch clssContext.Role {
+	case "", models.RoleUser, models.RoleSystem, models.RoleTool, models.RoleRetrievedDocument:
+	default:
+		return errors.New("role must be one of \"user\", \"system\", \"tool\" or \"retrieved_document\"")
+	}
+
+	if len(clssContext.ConversationID) > 128 {
+		return errors.New("conversation ID must not be longer than 128 characters")
+	}
+	if len(clssContext.SourceRef) > 2048 {
+		return errors.New("source reference must not be longer than 2048 characters")
+	}
+
+	if len(clssContext.Tags) > 20 {
+		return errors.New("a classification must not have more than 20 tags")
+	}
+	for _, tag := range clssContext.Tags {
+		if tag == "" || len(tag) > 64 {
+			return errors.New("tags must be between 1 and 64 characters long")
+		}
+	}
+
+	return nil
+}
+
+// logScope returns the classification logs the caller can access. Users access the logs of their tenant, while
+// external systems can only access the logs they created. Without claims, no logs can be accessed.
+func logScope(claims *models.AccessTokenClaims) dto.ClassificationLogScope {
+	if claims == nil {
+		return dto.ClassificationLogScope{}
+	}
+	if claims.Data["role"] == models.RoleExtSys {
+		return dto.SourceClassificationLogs(claims.TenantID, claims.Data["username"])
+	}
+
+	return dto.TenantClassificationLogs(claims.TenantID)
+}
+
+// GetClassificationRequestLogByID returns a classification log within the scope of the caller.
+func (s *ClassificationService) GetClassificationRequestLogByID(id uint, claims *models.AccessTokenClaims) (models.ClassificationLog, error) {
+	clssRequest, err := s.ClassificationLogsRepo.SelectClassificationLogByID(id, logScope(claims))
 	if err != nil {
 		return models.ClassificationLog{}, err
 	}
 	return clssRequest, nil
 }
 
-func (s *ClassificationService) GetClassificationLogsByPage(page int, limit int, sortBy string) ([]models.ClassificationLog, error) {
-	var orderBy string
+// sortableLogColumns are the columns of the classification logs that can be sorted by.
+var sortableLogColumns = map[string]bool{
+	"id":            true,
+	"created_at":    true,
+	"score":         true,
+	"result":        true,
+	"action":        true,
+	"source_name":   true,
+	"model_version": true,
+	"role":          true,
+}
+
+// GetClassificationLogsByPage returns a page of the classification logs matching the filter, sorted by the column in the given direction.
+// The total number of matching logs is only counted if includeTotal is set, since counting gets slow on large tables.
+func (s *ClassificationService) GetClassificationLogsByPage(page int, limit int, orderBy string, sortBy string, includeTotal bool, filter dto.ClassificationLogFilter, claims *models.AccessTokenClaims) (dto.ClassificationLogPage, error) {
+	scope := logScope(claims)
 
 	// Assure that the sortBy parameter is valid. Defaults to "desc" if it is not.
 	switch sortBy {
@@ -62,13 +534,36 @@ func (s *ClassificationService) GetClassificationLogsByPage(page int, limit int,
 		sortBy = "desc"
 	}
 
-	// Create orderBy parameter for the database query.
-	orderBy = fmt.Sprintf("id %s", sortBy)
+	// Only whitelisted columns are sorted by, since the column is inserted into the query. Defaults to "id".
+	if !sortableLogColumns[orderBy] {
+		orderBy = "id"
+	}
+
+	page = max(page, 1)
+	if limit < 1 {
+		limit = 15
+	}
+	limit = min(limit, maxLogsPageLimit)
+
+	// Create order parameter for the database query. The ID breaks ties, so the pages are stable.
+	order := fmt.Sprintf("%s %s", orderBy, sortBy)
+	if orderBy != "id" {
+		order = fmt.Sprintf("%s, id %s", order, sortBy)
+	}
 
-	clssRequests, err := s.ClassificationLogsRepo.SelectClassificationLogsByPage(page, limit, orderBy)
+	clssRequests, err := s.ClassificationLogsRepo.SelectClassificationLogsByPage(page, limit, order, filter, scope)
 	if err != nil {
-		return []models.ClassificationLog{}, err
+		return dto.ClassificationLogPage{}, err
+	}
+	clssPage := dto.ClassificationLogPage{Data: clssRequests, Page: page, Limit: limit}
+
+	if includeTotal {
+		clssPage.Total, err = s.ClassificationLogsRepo.CountClassificationLogs(filter, scope)
+		if err != nil {
+			return dto.ClassificationLogPage{}, err
+		}
+		clssPage.TotalPages = int((clssPage.Total + int64(limit) - 1) / int64(limit))
 	}
 
-	return clssRequests, nil
+	return clssPage, nil
 }
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"unicode/utf8"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// streamMaxLength is the maximum number of characters accepted by a single classification stream.
+const streamMaxLength = 1 << 20
+
+// ClassificationStream classifies incrementally received text (e.g. chat tokens) over a sliding window.
+// The whole stream is logged as a single classification log, correlated by the stream ID.
+type ClassificationStream struct {
+	ID                   string
+	service              *ClassificationService
+	tenantID             uint
+	sourceName           string
+	clssContext          models.ClassificationContext
+	terminateOnInjection bool
+
+	text            []rune
+	classifiedUntil int
+	sequence        int
+	worst           *models.ClassificationLog
+	terminated      bool
+}
+
+// NewClassificationStream opens a classification stream for a source of the tenant. The context is recorded in the log of the stream.
+// If terminateOnInjection is set, the stream is terminated on the first window classified as an injection.
+func (s *ClassificationService) NewClassificationStream(tenantID uint, sourceName string, clssContext models.ClassificationContext, terminateOnInjection bool) (*ClassificationStream, error) {
+	if err := validateClassificationContext(clssContext); err != nil {
+		return nil, err
+	}
+
+	streamID, err := newCorrelationID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClassificationStream{
+		ID:                   streamID,
+		service:              s,
+		tenantID:             tenantID,
+		sourceName:           sourceName,
+		clssContext:          clssContext,
+		terminateOnInjection: terminateOnInjection,
+	}, nil
+}
+
+// Terminated reports whether the stream was terminated early because of an injection.
+func (cs *ClassificationStream) Terminated() bool {
+	return cs.terminated
+}
+
+// Push appends a chunk to the stream and classifies the sliding window once enough new text was received.
+// It returns nil if the chunk did not trigger a classification.
+func (cs *ClassificationStream) Push(ctx context.Context, chunk string) (*dto.StreamVerdict, error) {
+	if cs.terminated {
+		return nil, errors.New("stream was terminated")
+	}
+	if len(cs.text)+utf8.RuneCountInString(chunk) > streamMaxLength {
+		return nil, errors.New("stream exceeds the maximum text length")
+	}
+
+	cs.text = append(cs.text, []rune(chunk)...)
+
+	if len(cs.text)-cs.classifiedUntil < cs.service.cfg.StreamStride {
+		return nil, nil
+	}
+
+	return cs.classifyWindow(ctx)
+}
+
+// Close classifies any remaining text and logs the whole stream as a single classification log.
+// It returns the verdict of the last window, if one was classified, and the stream log along with the risk state of its conversation.
+func (cs *ClassificationStream) Close(ctx context.Context) (*dto.StreamVerdict, dto.ClassificationResponse, error) {
+	var verdict *dto.StreamVerdict
+	var err error
+
+	if len(cs.text) == 0 {
+		return nil, dto.ClassificationResponse{}, errors.New("stream did not contain any text")
+	}
+
+	if !cs.terminated && cs.classifiedUntil < len(cs.text) {
+		verdict, err = cs.classifyWindow(ctx)
+		if err != nil {
+			return nil, dto.ClassificationResponse{}, err
+		}
+	}
+
+	// The stream is logged with the full text and the verdict of its riskiest window.
+	streamLog := *cs.worst
+	streamLog.RequestText = string(cs.text)
+	streamLog.CorrelationID = cs.ID
+	streamLog.ClassificationContext = cs.clssContext
+
+	if err := cs.service.ClassificationLogsRepo.InsertClassificationLog(&streamLog); err != nil {
+		return verdict, dto.ClassificationResponse{}, err
+	}
+	cs.service.mirrorToShadow(streamLog)
+	conversation, _ := cs.service.trackConversation(streamLog)
+
+	return verdict, dto.ClassificationResponse{ClassificationLog: streamLog, Conversation: conversation}, nil
+}
+
+// classifyWindow classifies the last StreamWindowSize characters of the stream.
+func (cs *ClassificationStream) classifyWindow(ctx context.Context) (*dto.StreamVerdict, error) {
+	windowStart := 0
+	if cs.service.cfg.StreamWindowSize > 0 && len(cs.text) > cs.service.cfg.StreamWindowSize {
+		windowStart = len(cs.text) - cs.service.cfg.StreamWindowSize
+	}
+	windowEnd := len(cs.text)
+
+	clssLog, err := cs.service.evaluate(ctx, cs.service.ClassificationRepo, dto.ClassificationRequest{Text: string(cs.text[windowStart:windowEnd])}, cs.tenantID, cs.sourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trigger spans are relative to the window. Shift them to the whole streamed text.
+	if clssLog.TriggerSpanStart != nil {
+		*clssLog.TriggerSpanStart += windowStart
+		*clssLog.TriggerSpanEnd += windowStart
+	}
+
+	cs.classifiedUntil = windowEnd
+	cs.sequence++
+
+	if cs.worst == nil || clssLog.Score > cs.worst.Score {
+		cs.worst = &clssLog
+	}
+
+	if cs.terminateOnInjection && clssLog.Result == "Injection" {
+		cs.terminated = true
+	}
+
+	return &dto.StreamVerdict{
+		Sequence:    cs.sequence,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+		Result:      clssLog.Result,
+		Score:       clssLog.Score,
+		Action:      clssLog.Action,
+		Terminated:  cs.terminated,
+	}, nil
+}
+
+// newCorrelationID generates a random identifier used to correlate related classification records.
+func newCorrelationID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.New("unable to generate correlation ID")
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
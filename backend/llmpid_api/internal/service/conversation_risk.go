@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+)
+
+// conversationTimelineLimit is the maximum number of turns returned in a conversation timeline.
+const conversationTimelineLimit = 1000
+
+// trackConversation adds a classified turn to the risk state of its conversation. Texts without a conversation ID are not tracked.
+func (s *ClassificationService) trackConversation(clssLog models.ClassificationLog) (*models.ConversationRisk, error) {
+	if clssLog.ConversationID == "" {
+		return nil, nil
+	}
+
+	risk, err := s.ConversationRepo.UpdateConversationRisk(clssLog.TenantID, clssLog.SourceName, clssLog.ConversationID, func(risk *models.ConversationRisk) {
+		addConversationTurn(risk, clssLog, s.conversationCfg, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &risk, nil
+}
+
+// addConversationTurn updates the risk state with a turn and decides the conversation-level action.
+// Only flagged or blocked turns add their score, since the scores of benign turns would add up over any long conversation.
+// The decayed score halves every HalfLife seconds, so a series of suspicious turns adds up while isolated ones fade out.
+// Likewise, the flagged turns are counted anew once no turn was flagged for HalfLife seconds.
+func addConversationTurn(risk *models.ConversationRisk, clssLog models.ClassificationLog, cfg config.ConversationConfiguration, now time.Time) {
+	halfLife := time.Duration(cfg.HalfLife) * time.Second
+	if risk.Turns > 0 && halfLife > 0 {
+		elapsed := max(now.Sub(risk.LastTurnAt), 0)
+		risk.DecayedScore *= math.Pow(0.5, elapsed.Seconds()/halfLife.Seconds())
+	}
+
+	risk.Turns++
+	risk.MaxScore = max(risk.MaxScore, clssLog.Score)
+	risk.LastTurnAt = now
+	if clssLog.Action != models.ActionAllow {
+		if halfLife > 0 && risk.LastFlaggedAt != nil && now.Sub(*risk.LastFlaggedAt) > halfLife {
+			risk.FlaggedTurns = 0
+		}
+		risk.FlaggedTurns++
+		risk.DecayedScore += clssLog.Score
+		risk.LastFlaggedAt = &now
+	}
+
+	switch {
+	case risk.DecayedScore >= cfg.BlockScore, cfg.MaxFlaggedTurns > 0 && risk.FlaggedTurns >= cfg.MaxFlaggedTurns:
+		risk.Action = models.ActionBlock
+	case risk.DecayedScore >= cfg.FlagScore:
+		risk.Action = models.ActionFlag
+	default:
+		risk.Action = models.ActionAllow
+	}
+}
+
+// GetConversationTimeline returns the risk state of a conversation of a source of the tenant along with the logs of its turns.
+func (s *ClassificationService) GetConversationTimeline(tenantID uint, sourceName string, conversationID string) (dto.ConversationTimeline, error) {
+	risk, err := s.ConversationRepo.SelectConversationRisk(tenantID, sourceName, conversationID)
+	if err != nil {
+		return dto.ConversationTimeline{}, err
+	}
+	if risk == nil {
+		return dto.ConversationTimeline{}, errors.New("conversation not found")
+	}
+
+	turns, err := s.ClassificationLogsRepo.SelectClassificationLogsByConversation(dto.TenantClassificationLogs(tenantID), sourceName, conversationID, conversationTimelineLimit)
+	if err != nil {
+		return dto.ConversationTimeline{}, err
+	}
+
+	return dto.ConversationTimeline{Conversation: *risk, Turns: turns}, nil
+}
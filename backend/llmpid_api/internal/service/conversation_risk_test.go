@@ -0,0 +1,112 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/models"
+)
+
+func TestAddConversationTurn(t *testing.T) {
+	cfg := config.ConversationConfiguration{HalfLife: 600, FlagScore: 1.5, BlockScore: 2.5, MaxFlaggedTurns: 3}
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	type turn struct {
+		at     time.Duration // Time of the turn after the start of the conversation.
+		score  float64
+		action string
+	}
+
+	tests := []struct {
+		name             string
+		cfg              config.ConversationConfiguration
+		turns            []turn
+		wantDecayedScore float64
+		wantFlaggedTurns int
+		wantAction       string
+	}{
+		{
+			name: "benign turns do not add up",
+			cfg:  cfg,
+			turns: []turn{
+				{0, 0.4, models.ActionAllow}, {10 * time.Second, 0.4, models.ActionAllow}, {20 * time.Second, 0.4, models.ActionAllow},
+				{30 * time.Second, 0.4, models.ActionAllow}, {40 * time.Second, 0.4, models.ActionAllow},
+			},
+			wantDecayedScore: 0,
+			wantFlaggedTurns: 0,
+			wantAction:       models.ActionAllow,
+		},
+		{
+			name:             "flagged turns add up",
+			cfg:              cfg,
+			turns:            []turn{{0, 0.8, models.ActionFlag}, {0, 0.8, models.ActionFlag}},
+			wantDecayedScore: 1.6,
+			wantFlaggedTurns: 2,
+			wantAction:       models.ActionFlag,
+		},
+		{
+			name:             "score halves every half-life",
+			cfg:              cfg,
+			turns:            []turn{{0, 0.9, models.ActionFlag}, {600 * time.Second, 0.9, models.ActionFlag}},
+			wantDecayedScore: 1.35,
+			wantFlaggedTurns: 2,
+			wantAction:       models.ActionAllow,
+		},
+		{
+			name:             "block score",
+			cfg:              config.ConversationConfiguration{HalfLife: 600, FlagScore: 1.5, BlockScore: 2.5},
+			turns:            []turn{{0, 1, models.ActionBlock}, {0, 1, models.ActionBlock}, {0, 1, models.ActionBlock}},
+			wantDecayedScore: 3,
+			wantFlaggedTurns: 3,
+			wantAction:       models.ActionBlock,
+		},
+		{
+			name:             "flagged turns block the conversation",
+			cfg:              cfg,
+			turns:            []turn{{0, 0.5, models.ActionFlag}, {time.Second, 0.5, models.ActionFlag}, {2 * time.Second, 0.5, models.ActionFlag}},
+			wantDecayedScore: 1.4988,
+			wantFlaggedTurns: 3,
+			wantAction:       models.ActionBlock,
+		},
+		{
+			name:             "flagged turns are counted anew after a half-life",
+			cfg:              cfg,
+			turns:            []turn{{0, 0.5, models.ActionFlag}, {time.Second, 0.5, models.ActionFlag}, {1000 * time.Second, 0.5, models.ActionFlag}},
+			wantDecayedScore: 0.8155,
+			wantFlaggedTurns: 1,
+			wantAction:       models.ActionAllow,
+		},
+		{
+			name:             "no decay without a half-life",
+			cfg:              config.ConversationConfiguration{FlagScore: 1.5, BlockScore: 2.5},
+			turns:            []turn{{0, 0.8, models.ActionFlag}, {time.Hour, 0.8, models.ActionFlag}},
+			wantDecayedScore: 1.6,
+			wantFlaggedTurns: 2,
+			wantAction:       models.ActionFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var risk models.ConversationRisk
+			for _, turn := range tt.turns {
+				addConversationTurn(&risk, models.ClassificationLog{Score: turn.score, Action: turn.action}, tt.cfg, start.Add(turn.at))
+			}
+
+			if risk.Turns != len(tt.turns) {
+				t.Errorf("Turns = %d, want %d", risk.Turns, len(tt.turns))
+			}
+			if math.Abs(risk.DecayedScore-tt.wantDecayedScore) > 1e-3 {
+				t.Errorf("DecayedScore = %v, want %v", risk.DecayedScore, tt.wantDecayedScore)
+			}
+			if risk.FlaggedTurns != tt.wantFlaggedTurns {
+				t.Errorf("FlaggedTurns = %d, want %d", risk.FlaggedTurns, tt.wantFlaggedTurns)
+			}
+			if risk.Action != tt.wantAction {
+				t.Errorf("Action = %s, want %s", risk.Action, tt.wantAction)
+			}
+		})
+	}
+}
@@ -1,21 +1,30 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	"github.com/sirupsen/logrus"
+	"llm-promp-inj.api/config"
+	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/repository"
 )
 
 type ExternalSystemService struct {
-	CryptoRepo *repository.CryptoRepository
-	UserRepo   *repository.UserRepository
-	logger     *logrus.Logger
+	CryptoRepo  *repository.CryptoRepository
+	UserRepo    *repository.UserRepository
+	PolicyRepo  *repository.PolicyRepository
+	WebhookRepo *repository.WebhookRepository
+	jobsCfg     config.JobsConfiguration
+	logger      *logrus.Logger
 }
 
-func NewExternalSystemService(cryptoRepo *repository.CryptoRepository, userRepo *repository.UserRepository, logger *logrus.Logger) *ExternalSystemService {
-	return &ExternalSystemService{CryptoRepo: cryptoRepo, UserRepo: userRepo, logger: logger}
+func NewExternalSystemService(cryptoRepo *repository.CryptoRepository, userRepo *repository.UserRepository, policyRepo *repository.PolicyRepository, webhookRepo *repository.WebhookRepository, jobsCfg config.JobsConfiguration, logger *logrus.Logger) *ExternalSystemService {
+	return &ExternalSystemService{CryptoRepo: cryptoRepo, UserRepo: userRepo, PolicyRepo: policyRepo, WebhookRepo: webhookRepo, jobsCfg: jobsCfg, logger: logger}
 }
 
-func (s *ExternalSystemService) Register(systemName string) (string, error) {
+// Register creates an external system in the tenant and returns its access key.
+func (s *ExternalSystemService) Register(tenantID uint, systemName string) (string, error) {
 	systemAccessKey, err := s.CryptoRepo.GenrateRandomString(32)
 	if err != nil {
 		return "", err
@@ -30,7 +39,7 @@ func (s *ExternalSystemService) Register(systemName string) (string, error) {
 
 	// The external system is treated as user with a role "ext_sys" internally.
 	// Therefore, it is inserted in the users table.
-	_, err = s.UserRepo.InsertUser(systemName, systemKeyHash, "ext_sys")
+	_, err = s.UserRepo.InsertUser(tenantID, systemName, systemKeyHash, "ext_sys")
 	if err != nil {
 		return "", err
 	}
@@ -38,19 +47,24 @@ func (s *ExternalSystemService) Register(systemName string) (string, error) {
 	return systemAccessKey, nil
 }
 
-func (s *ExternalSystemService) Update(oldServiceName string, newServiceName string) error {
-	_, err := s.UserRepo.UpdateUsername(oldServiceName, newServiceName)
+func (s *ExternalSystemService) Update(tenantID uint, oldServiceName string, newServiceName string) error {
+	_, err := s.UserRepo.UpdateUsername(tenantID, oldServiceName, newServiceName)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Keep the classification policy and webhook attached to the renamed system.
+	if err := s.PolicyRepo.UpdateSourceName(tenantID, oldServiceName, newServiceName); err != nil {
+		return err
+	}
+
+	return s.WebhookRepo.UpdateSourceName(tenantID, oldServiceName, newServiceName)
 }
 
-func (s *ExternalSystemService) List() ([]string, error) {
+func (s *ExternalSystemService) List(tenantID uint) ([]string, error) {
 	var services []string
 
-	serviceUsers, err := s.UserRepo.SelectUserByRole("ext_sys")
+	serviceUsers, err := s.UserRepo.SelectUserByRole(tenantID, "ext_sys")
 	if err != nil {
 		return []string{}, err
 	}
@@ -62,7 +76,35 @@ func (s *ExternalSystemService) List() ([]string, error) {
 	return services, nil
 }
 
-func (s *ExternalSystemService) DeleteBySysName(username string) error {
+func (s *ExternalSystemService) DeleteBySysName(tenantID uint, username string) error {
+	return s.UserRepo.DeleteExternalSystem(tenantID, username)
+}
+
+// RegisterWebhook sets the URL to which the results of the system's classification jobs are delivered.
+// The URL must resolve to public addresses, unless its host is on the allow-list. It returns the secret used to sign the deliveries.
+func (s *ExternalSystemService) RegisterWebhook(ctx context.Context, tenantID uint, systemName string, webhookURL string) (string, error) {
+	if err := checkWebhookURL(ctx, webhookURL, s.jobsCfg.WebhookAllowedHosts); err != nil {
+		return "", err
+	}
+
+	user, err := s.UserRepo.SelectTenantUserByUsername(tenantID, systemName)
+	if err != nil || user.Role != "ext_sys" {
+		return "", errors.New("external system not found")
+	}
+
+	secret, err := s.CryptoRepo.GenrateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.WebhookRepo.UpsertWebhook(models.Webhook{TenantID: tenantID, SourceName: systemName, URL: webhookURL, Secret: secret})
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
 
-	return s.UserRepo.DeleteByUsername(username)
+func (s *ExternalSystemService) DeleteWebhook(tenantID uint, systemName string) error {
+	return s.WebhookRepo.DeleteBySourceName(tenantID, systemName)
 }
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/repository"
+)
+
+// ErrPolicyNotFound is returned when an external system has no classification policy.
+var ErrPolicyNotFound = errors.New("policy not found")
+
+type PolicyService struct {
+	PolicyRepo *repository.PolicyRepository
+	UserRepo   *repository.UserRepository
+}
+
+func NewPolicyService(policyRepo *repository.PolicyRepository, userRepo *repository.UserRepository) *PolicyService {
+	return &PolicyService{PolicyRepo: policyRepo, UserRepo: userRepo}
+}
+
+// Create registers a classification policy for an existing external system of the tenant.
+func (s *PolicyService) Create(tenantID uint, policyRequest dto.PolicyRequest) (models.ClassificationPolicy, error) {
+	if err := validatePolicyRequest(policyRequest); err != nil {
+		return models.ClassificationPolicy{}, err
+	}
+
+	// Policies apply only to external systems (users with the "ext_sys" role).
+	user, err := s.UserRepo.SelectTenantUserByUsername(tenantID, policyRequest.SourceName)
+	if err != nil || user.Role != "ext_sys" {
+		return models.ClassificationPolicy{}, errors.New("external system not found")
+	}
+
+	existingPolicy, err := s.PolicyRepo.SelectPolicyBySourceName(tenantID, policyRequest.SourceName)
+	if err != nil {
+		return models.ClassificationPolicy{}, err
+	}
+	if existingPolicy != nil {
+		return models.ClassificationPolicy{}, errors.New("policy already exists for the external system")
+	}
+
+	return s.PolicyRepo.InsertPolicy(models.ClassificationPolicy{
+		TenantID:       tenantID,
+		SourceName:     policyRequest.SourceName,
+		Threshold:      policyRequest.Threshold,
+		FlagThreshold:  policyRequest.FlagThreshold,
+		BlockThreshold: policyRequest.BlockThreshold,
+		FailMode:       policyRequest.FailMode,
+	})
+}
+
+func (s *PolicyService) List(tenantID uint) ([]models.ClassificationPolicy, error) {
+	return s.PolicyRepo.SelectPolicies(tenantID)
+}
+
+func (s *PolicyService) Get(tenantID uint, sourceName string) (models.ClassificationPolicy, error) {
+	policy, err := s.PolicyRepo.SelectPolicyBySourceName(tenantID, sourceName)
+	if err != nil {
+		return models.ClassificationPolicy{}, err
+	}
+	if policy == nil {
+		return models.ClassificationPolicy{}, ErrPolicyNotFound
+	}
+
+	return *policy, nil
+}
+
+// Update replaces the thresholds of the policy of an external system.
+func (s *PolicyService) Update(tenantID uint, sourceName string, policyRequest dto.PolicyRequest) (models.ClassificationPolicy, error) {
+	policyRequest.SourceName = sourceName
+	if err := validatePolicyRequest(policyRequest); err != nil {
+		return models.ClassificationPolicy{}, err
+	}
+
+	policy, err := s.Get(tenantID, sourceName)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.Threshold = policyRequest.Threshold
+	policy.FlagThreshold = policyRequest.FlagThreshold
+	policy.BlockThreshold = policyRequest.BlockThreshold
+	policy.FailMode = policyRequest.FailMode
+
+	if err := s.PolicyRepo.UpdatePolicy(policy); err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+func (s *PolicyService) Delete(tenantID uint, sourceName string) error {
+	return s.PolicyRepo.DeleteBySourceName(tenantID, sourceName)
+}
+
+// validatePolicyRequest assures that all thresholds are valid scores and that the score ranges are ordered.
+// A threshold of 0 is rejected, since it would flag or block every text.
+func validatePolicyRequest(policyRequest dto.PolicyRequest) error {
+	if policyRequest.SourceName == "" {
+		return errors.New("source name is required")
+	}
+
+	for _, threshold := range []float64{policyRequest.Threshold, policyRequest.FlagThreshold, policyRequest.BlockThreshold} {
+		if threshold <= 0 || threshold > 1 {
+			return errors.New("thresholds must be greater than 0 and at most 1")
+		}
+	}
+
+	if policyRequest.FlagThreshold > policyRequest.BlockThreshold {
+		return errors.New("flag threshold must not be greater than the block threshold")
+	}
+
+	switch policyRequest.FailMode {
+	case "", models.FailModeOpen, models.FailModeClosed:
+	default:
+		return errors.New("fail mode must be either \"open\" or \"closed\"")
+	}
+
+	return nil
+}
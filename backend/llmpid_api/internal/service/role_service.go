@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/repository"
+)
+
+// rolePermissionsTTL bounds how long the permissions of a role are cached. Changes made through this instance apply right away,
+// changes made through other instances apply once the cached permissions expire.
+const rolePermissionsTTL = 30 * time.Second
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+type RoleService struct {
+	RoleRepo *repository.RoleRepository
+	UserRepo *repository.UserRepository
+
+	permissionsMu sync.RWMutex
+	permissions   map[string]cachedPermissions
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository) *RoleService {
+	return &RoleService{RoleRepo: roleRepo, UserRepo: userRepo, permissions: make(map[string]cachedPermissions)}
+}
+
+// invalidatePermissions drops the cached permissions of all roles.
+func (s *RoleService) invalidatePermissions() {
+	s.permissionsMu.Lock()
+	clear(s.permissions)
+	s.permissionsMu.Unlock()
+}
+
+// EnsureBuiltInRoles creates the built-in roles and resets any changes made to them in the database.
+func (s *RoleService) EnsureBuiltInRoles() error {
+	for _, role := range models.BuiltInRoles {
+		role.BuiltIn = true
+		if err := s.RoleRepo.UpsertRole(role); err != nil {
+			return err
+		}
+	}
+	s.invalidatePermissions()
+
+	return nil
+}
+
+// Permissions returns the permissions granted by a role. Unknown roles grant no permissions.
+// They are looked up on every request, so they are cached for rolePermissionsTTL.
+func (s *RoleService) Permissions(roleName string) ([]string, error) {
+	s.permissionsMu.RLock()
+	cached, ok := s.permissions[roleName]
+	s.permissionsMu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	role, err := s.RoleRepo.SelectRoleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	if role != nil {
+		permissions = role.Permissions
+	}
+
+	s.permissionsMu.Lock()
+	s.permissions[roleName] = cachedPermissions{permissions: permissions, expiresAt: time.Now().Add(rolePermissionsTTL)}
+	s.permissionsMu.Unlock()
+
+	return permissions, nil
+}
+
+func (s *RoleService) Create(roleRequest dto.RoleRequest) (models.Role, error) {
+	if err := validateRoleRequest(roleRequest); err != nil {
+		return models.Role{}, err
+	}
+
+	existingRole, err := s.RoleRepo.SelectRoleByName(roleRequest.Name)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if existingRole != nil {
+		return models.Role{}, errors.New("role already exists")
+	}
+
+	role, err := s.RoleRepo.InsertRole(models.Role{
+		Name:        roleRequest.Name,
+		Description: roleRequest.Description,
+		Permissions: roleRequest.Permissions,
+	})
+	if err != nil {
+		return role, err
+	}
+	// An unknown role may have been cached without permissions.
+	s.invalidatePermissions()
+
+	return role, nil
+}
+
+func (s *RoleService) List() ([]models.Role, error) {
+	return s.RoleRepo.SelectRoles()
+}
+
+func (s *RoleService) Get(name string) (models.Role, error) {
+	role, err := s.RoleRepo.SelectRoleByName(name)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if role == nil {
+		return models.Role{}, errors.New("role not found")
+	}
+
+	return *role, nil
+}
+
+// Update replaces the description and the permissions of a custom role.
+// The permissions of users with the role change with their next request to this instance.
+func (s *RoleService) Update(name string, roleRequest dto.RoleRequest) (models.Role, error) {
+	roleRequest.Name = name
+	if err := validateRoleRequest(roleRequest); err != nil {
+		return models.Role{}, err
+	}
+
+	role, err := s.Get(name)
+	if err != nil {
+		return role, err
+	}
+	if role.BuiltIn {
+		return role, errors.New("built-in roles cannot be changed")
+	}
+
+	role.Description = roleRequest.Description
+	role.Permissions = roleRequest.Permissions
+	if err := s.RoleRepo.UpdateRole(role); err != nil {
+		return role, err
+	}
+	s.invalidatePermissions()
+
+	return s.Get(name)
+}
+
+// Delete removes a custom role that is not assigned to any user.
+func (s *RoleService) Delete(name string) error {
+	role, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	users, err := s.UserRepo.CountUsersByRole(name)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return errors.New("role is assigned to users")
+	}
+
+	if err := s.RoleRepo.DeleteRoleByName(name); err != nil {
+		return err
+	}
+	s.invalidatePermissions()
+
+	return nil
+}
+
+func validateRoleRequest(roleRequest dto.RoleRequest) error {
+	if err := dto.Validate(roleRequest); err != nil {
+		return err
+	}
+	if len(roleRequest.Permissions) == 0 {
+		return errors.New("permissions must contain at least one permission")
+	}
+	for _, permission := range roleRequest.Permissions {
+		if !slices.Contains(models.Permissions, permission) {
+			return fmt.Errorf("unknown permission: %s", permission)
+		}
+	}
+	// Only external systems authenticate to manage themselves.
+	if slices.Contains(roleRequest.Permissions, models.PermSystemsSelf) {
+		return fmt.Errorf("permission %s is reserved for external systems", models.PermSystemsSelf)
+	}
+
+	return nil
+}
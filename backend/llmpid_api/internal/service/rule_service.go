@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/repository"
+)
+
+// ErrRuleNotFound is returned when there is no detection rule with the requested ID.
+var ErrRuleNotFound = errors.New("rule not found")
+
+// ErrRuleInFile is returned when a rule of the rules file is changed through the API. Such rules are edited in the file.
+var ErrRuleInFile = errors.New("rule is defined in the rules file")
+
+type RuleService struct {
+	RuleRepo *repository.RuleRepository
+}
+
+func NewRuleService(ruleRepo *repository.RuleRepository) *RuleService {
+	return &RuleService{RuleRepo: ruleRepo}
+}
+
+func (s *RuleService) List() ([]models.DetectionRule, error) {
+	return s.RuleRepo.SelectRules()
+}
+
+func (s *RuleService) Get(id string) (models.DetectionRule, error) {
+	rule, err := s.RuleRepo.SelectRuleByID(id)
+	if err != nil {
+		return models.DetectionRule{}, err
+	}
+	if rule == nil {
+		return models.DetectionRule{}, ErrRuleNotFound
+	}
+
+	return *rule, nil
+}
+
+// Create adds a detection rule. It is active immediately on this replica and picked up by the others on their next reload.
+func (s *RuleService) Create(ruleRequest dto.RuleRequest) (models.DetectionRule, error) {
+	existingRule, err := s.RuleRepo.SelectRuleByID(ruleRequest.ID)
+	if err != nil {
+		return models.DetectionRule{}, err
+	}
+	if existingRule != nil {
+		return models.DetectionRule{}, errors.New("rule already exists")
+	}
+
+	rule := ruleFromRequest(ruleRequest)
+	if err := s.RuleRepo.InsertRule(rule); err != nil {
+		return models.DetectionRule{}, err
+	}
+
+	return s.Get(rule.ID)
+}
+
+func (s *RuleService) Update(id string, ruleRequest dto.RuleRequest) (models.DetectionRule, error) {
+	ruleRequest.ID = id
+
+	existingRule, err := s.Get(id)
+	if err != nil {
+		return models.DetectionRule{}, err
+	}
+	if existingRule.Source == models.RuleSourceFile {
+		return models.DetectionRule{}, ErrRuleInFile
+	}
+
+	rule := ruleFromRequest(ruleRequest)
+	if err := s.RuleRepo.UpdateRule(rule); err != nil {
+		return models.DetectionRule{}, err
+	}
+
+	return s.Get(rule.ID)
+}
+
+func (s *RuleService) Delete(id string) error {
+	existingRule, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if existingRule.Source == models.RuleSourceFile {
+		return ErrRuleInFile
+	}
+
+	return s.RuleRepo.DeleteRule(id)
+}
+
+// Reload loads the rules from the rules file and the database again.
+func (s *RuleService) Reload() error {
+	return s.RuleRepo.Reload()
+}
+
+// ruleFromRequest builds a rule from the request. Rules without a score classify matching texts with a score of 1.
+func ruleFromRequest(ruleRequest dto.RuleRequest) models.DetectionRule {
+	score := ruleRequest.Score
+	if score == 0 {
+		score = 1
+	}
+
+	return models.DetectionRule{
+		ID:          ruleRequest.ID,
+		Description: ruleRequest.Description,
+		Type:        ruleRequest.Type,
+		Pattern:     ruleRequest.Pattern,
+		Phrases:     ruleRequest.Phrases,
+		Score:       score,
+		Action:      ruleRequest.Action,
+		Disabled:    ruleRequest.Disabled,
+	}
+}
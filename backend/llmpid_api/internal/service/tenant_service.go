@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/models"
+	"llm-promp-inj.api/internal/repository"
+)
+
+type TenantService struct {
+	TenantRepo *repository.TenantRepository
+	UserRepo   *repository.UserRepository
+	CryptoRepo *repository.CryptoRepository
+}
+
+func NewTenantService(tenantRepo *repository.TenantRepository, userRepo *repository.UserRepository, cryptoRepo *repository.CryptoRepository) *TenantService {
+	return &TenantService{TenantRepo: tenantRepo, UserRepo: userRepo, CryptoRepo: cryptoRepo}
+}
+
+// Create creates a tenant along with its first admin, who manages the users and external systems of the tenant.
+func (s *TenantService) Create(tenantRequest dto.CreateTenantRequest) (models.Tenant, error) {
+	if err := dto.Validate(tenantRequest); err != nil {
+		return models.Tenant{}, err
+	}
+
+	existingTenant, err := s.TenantRepo.SelectTenantByName(tenantRequest.Name)
+	if err != nil {
+		return models.Tenant{}, err
+	}
+	if existingTenant != nil {
+		return models.Tenant{}, errors.New("tenant already exists")
+	}
+
+	// Usernames are unique across tenants, since users log in without naming their tenant.
+	if _, err := s.UserRepo.SelectUserByUsername(tenantRequest.AdminUsername); err == nil {
+		return models.Tenant{}, errors.New("username is already taken")
+	}
+
+	passwordHash, err := s.CryptoRepo.HashSaltString(tenantRequest.AdminPassword)
+	if err != nil {
+		return models.Tenant{}, err
+	}
+
+	return s.TenantRepo.InsertTenantWithAdmin(
+		models.Tenant{Name: tenantRequest.Name},
+		models.User{Username: tenantRequest.AdminUsername, PasswordHash: passwordHash, Role: models.RoleAdmin},
+	)
+}
+
+func (s *TenantService) List() ([]models.Tenant, error) {
+	return s.TenantRepo.SelectTenants()
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"unicode"
+
+	"llm-promp-inj.api/internal/dto"
+)
+
+// Strategies for aggregating the results of the chunks of a long text.
+const (
+	AggregationMaxScore     = "max_score"
+	AggregationAnyInjection = "any_injection"
+	AggregationMajority     = "majority"
+)
+
+// textChunk is a part of a classified text. Start and End are character offsets within the whole text.
+type textChunk struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// chunkText splits a text into chunks of up to size words, where consecutive chunks share overlap words.
+// Texts that are not longer than a single chunk (or a size of 0) result in a single chunk containing the whole text.
+func chunkText(text string, size int, overlap int) []textChunk {
+	runes := []rune(text)
+
+	// Find the character offsets of every word in the text.
+	var words [][2]int
+	wordStart := -1
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			if wordStart >= 0 {
+				words = append(words, [2]int{wordStart, i})
+				wordStart = -1
+			}
+		} else if wordStart < 0 {
+			wordStart = i
+		}
+	}
+	if wordStart >= 0 {
+		words = append(words, [2]int{wordStart, len(runes)})
+	}
+
+	if size <= 0 || len(words) <= size {
+		return []textChunk{{Text: text, Start: 0, End: len(runes)}}
+	}
+
+	step := size - overlap
+	if step <= 0 {
+		step = size
+	}
+
+	var chunks []textChunk
+	for i := 0; i < len(words); i += step {
+		last := min(i+size, len(words)) - 1
+		start, end := words[i][0], words[last][1]
+		chunks = append(chunks, textChunk{Text: string(runes[start:end]), Start: start, End: end})
+
+		if last == len(words)-1 {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// aggregateChunkResults combines the results of all chunks into a single result with the given strategy.
+// It also returns the index of the chunk that triggered the verdict.
+func aggregateChunkResults(results []dto.ClassifierResult, strategy string) (dto.ClassifierResult, int) {
+	// The chunk with the highest score decides the verdict of the "max_score" strategy,
+	// and is reported as the trigger of the other strategies.
+	trigger := 0
+	injections := 0
+	var scoreSum float64
+	for i, result := range results {
+		if result.Score > results[trigger].Score {
+			trigger = i
+		}
+		if result.Result == "Injection" {
+			injections++
+		}
+		scoreSum += result.Score
+	}
+
+	aggregated := results[trigger]
+
+	switch strategy {
+	case AggregationAnyInjection:
+		aggregated.Result = "Normal"
+		if injections > 0 {
+			aggregated.Result = "Injection"
+		}
+	case AggregationMajority:
+		aggregated.Score = scoreSum / float64(len(results))
+		aggregated.Result = "Normal"
+		if injections*2 > len(results) {
+			aggregated.Result = "Injection"
+		}
+	}
+
+	return aggregated, trigger
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"llm-promp-inj.api/internal/dto"
+)
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    []textChunk
+	}{
+		{
+			name: "short text is a single chunk",
+			text: "a b c",
+			size: 5,
+			want: []textChunk{{Text: "a b c", Start: 0, End: 5}},
+		},
+		{
+			name: "size of 0 disables chunking",
+			text: "a b c d e f",
+			size: 0,
+			want: []textChunk{{Text: "a b c d e f", Start: 0, End: 11}},
+		},
+		{
+			name:    "consecutive chunks share the overlap",
+			text:    "one two three four five",
+			size:    2,
+			overlap: 1,
+			want: []textChunk{
+				{Text: "one two", Start: 0, End: 7},
+				{Text: "two three", Start: 4, End: 13},
+				{Text: "three four", Start: 8, End: 18},
+				{Text: "four five", Start: 14, End: 23},
+			},
+		},
+		{
+			name:    "overlap not smaller than the size is ignored",
+			text:    "a b c d e",
+			size:    2,
+			overlap: 2,
+			want: []textChunk{
+				{Text: "a b", Start: 0, End: 3},
+				{Text: "c d", Start: 4, End: 7},
+				{Text: "e", Start: 8, End: 9},
+			},
+		},
+		{
+			name: "offsets count characters rather than bytes",
+			text: "  héllo wörld  foo",
+			size: 2,
+			want: []textChunk{
+				{Text: "héllo wörld", Start: 2, End: 13},
+				{Text: "foo", Start: 15, End: 18},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkText(tt.text, tt.size, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkText() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateChunkResults(t *testing.T) {
+	oneInjection := []dto.ClassifierResult{
+		{Result: "Normal", Score: 0.25, Threshold: 0.5},
+		{Result: "Injection", Score: 0.75, Threshold: 0.5},
+		{Result: "Normal", Score: 0.5, Threshold: 0.5},
+	}
+	twoInjections := []dto.ClassifierResult{
+		{Result: "Injection", Score: 0.75, Threshold: 0.5},
+		{Result: "Injection", Score: 0.5, Threshold: 0.5},
+		{Result: "Normal", Score: 0.25, Threshold: 0.5},
+	}
+
+	tests := []struct {
+		name        string
+		results     []dto.ClassifierResult
+		strategy    string
+		wantResult  string
+		wantScore   float64
+		wantTrigger int
+	}{
+		{name: "max score takes the highest scoring chunk", results: oneInjection, strategy: AggregationMaxScore, wantResult: "Injection", wantScore: 0.75, wantTrigger: 1},
+		{name: "any injection", results: oneInjection, strategy: AggregationAnyInjection, wantResult: "Injection", wantScore: 0.75, wantTrigger: 1},
+		{name: "any injection without injections", results: oneInjection[:1], strategy: AggregationAnyInjection, wantResult: "Normal", wantScore: 0.25, wantTrigger: 0},
+		{name: "majority outvotes a single injection", results: oneInjection, strategy: AggregationMajority, wantResult: "Normal", wantScore: 0.5, wantTrigger: 1},
+		{name: "majority of injections", results: twoInjections, strategy: AggregationMajority, wantResult: "Injection", wantScore: 0.5, wantTrigger: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trigger := aggregateChunkResults(tt.results, tt.strategy)
+			if got.Result != tt.wantResult || got.Score != tt.wantScore || trigger != tt.wantTrigger {
+				t.Errorf("aggregateChunkResults() = %s %v (trigger %d), want %s %v (trigger %d)",
+					got.Result, got.Score, trigger, tt.wantResult, tt.wantScore, tt.wantTrigger)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Transformations of the normalization pipeline, applied in this order.
+const (
+	TransformationOriginal    = "original"
+	TransformationZeroWidth   = "zero_width"
+	TransformationUnicode     = "unicode"
+	TransformationConfusables = "confusables"
+	TransformationLeetspeak   = "leetspeak"
+	TransformationBase64      = "base64"
+	TransformationHex         = "hex"
+)
+
+// textVariant is a version of the classified text produced by the normalization pipeline.
+type textVariant struct {
+	Transformation string
+	Text           string
+}
+
+// characterTransformations rewrite the text character by character. They are applied cumulatively.
+var characterTransformations = []struct {
+	name      string
+	transform func(string) string
+}{
+	{TransformationZeroWidth, removeZeroWidth},
+	{TransformationUnicode, norm.NFKC.String},
+	{TransformationConfusables, replaceConfusables},
+	{TransformationLeetspeak, replaceLeetspeak},
+}
+
+// payloadDecoders extract payloads that are embedded in the text in an encoded form.
+var payloadDecoders = []struct {
+	name   string
+	decode func(string) []string
+}{
+	{TransformationBase64, decodeBase64Payloads},
+	{TransformationHex, decodeHexPayloads},
+}
+
+var (
+	base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}`)
+	hexPattern    = regexp.MustCompile(`(?:0x)?(?:[0-9a-fA-F]{2}){8,}`)
+)
+
+// confusables maps common homoglyphs (mostly Cyrillic and Greek) to the Latin letters they imitate.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ɡ': 'g',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T',
+	'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S', 'α': 'a', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I',
+	'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// leetspeak maps characters commonly used in leetspeak to the letters they replace.
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '@': 'a', '$': 's', '!': 'i',
+}
+
+// normalizeText runs the enabled transformations over the text and returns all distinct variants, starting with the original text.
+// Character transformations are cumulative, so each variant contains the changes of the previous ones and is named after all of them
+// (e.g. "zero_width+confusables"). Decoded payloads are extracted from the fully normalized text.
+func normalizeText(text string, transformations []string) []textVariant {
+	variants := []textVariant{{Transformation: TransformationOriginal, Text: text}}
+
+	enabled := make(map[string]bool, len(transformations))
+	for _, transformation := range transformations {
+		enabled[transformation] = true
+	}
+
+	normalized := text
+	var applied []string
+	for _, transformation := range characterTransformations {
+		if !enabled[transformation.name] {
+			continue
+		}
+
+		transformed := transformation.transform(normalized)
+		if transformed == normalized {
+			continue
+		}
+
+		normalized = transformed
+		applied = append(applied, transformation.name)
+		variants = append(variants, textVariant{Transformation: strings.Join(applied, "+"), Text: normalized})
+	}
+
+	// Payloads are decoded from the text without zero-width characters, but before lossy replacements such as leetspeak.
+	decodable := removeZeroWidth(text)
+	for _, decoder := range payloadDecoders {
+		if !enabled[decoder.name] {
+			continue
+		}
+
+		payloads := decoder.decode(decodable)
+		if len(payloads) > 0 {
+			variants = append(variants, textVariant{Transformation: decoder.name, Text: strings.Join(payloads, "\n")})
+		}
+	}
+
+	return variants
+}
+
+// removeZeroWidth removes invisible format characters (e.g. zero-width spaces and joiners, soft hyphens and bidi controls)
+// that are used to split words without changing how the text looks.
+func removeZeroWidth(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+func replaceConfusables(text string) string {
+	return strings.Map(func(r rune) rune {
+		if replacement, ok := confusables[r]; ok {
+			return replacement
+		}
+		return r
+	}, text)
+}
+
+func replaceLeetspeak(text string) string {
+	return strings.Map(func(r rune) rune {
+		if replacement, ok := leetspeak[r]; ok {
+			return replacement
+		}
+		return r
+	}, text)
+}
+
+func decodeBase64Payloads(text string) []string {
+	var payloads []string
+
+	for _, candidate := range base64Pattern.FindAllString(text, -1) {
+		trimmed := strings.TrimRight(candidate, "=")
+		for _, encoding := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+			decoded, err := encoding.DecodeString(trimmed)
+			if err == nil && isReadableText(decoded) {
+				payloads = append(payloads, string(decoded))
+				break
+			}
+		}
+	}
+
+	return payloads
+}
+
+func decodeHexPayloads(text string) []string {
+	var payloads []string
+
+	for _, candidate := range hexPattern.FindAllString(text, -1) {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(candidate, "0x"))
+		if err == nil && isReadableText(decoded) {
+			payloads = append(payloads, string(decoded))
+		}
+	}
+
+	return payloads
+}
+
+// isReadableText reports whether decoded bytes are valid UTF-8 text made of printable characters.
+// It filters out random tokens (e.g. IDs and hashes) that happen to be valid base64 or hex.
+func isReadableText(decoded []byte) bool {
+	if !utf8.Valid(decoded) {
+		return false
+	}
+
+	var printable, total int
+	for _, r := range string(decoded) {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+
+	return total > 0 && printable*10 >= total*9
+}
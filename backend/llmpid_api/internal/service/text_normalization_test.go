@@ -0,0 +1,93 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	allTransformations := []string{
+		TransformationZeroWidth, TransformationUnicode, TransformationConfusables, TransformationLeetspeak, TransformationBase64, TransformationHex,
+	}
+
+	tests := []struct {
+		name            string
+		text            string
+		transformations []string
+		want            []textVariant
+	}{
+		{
+			name:            "plain text has no further variants",
+			text:            "hello world",
+			transformations: allTransformations,
+			want:            []textVariant{{Transformation: TransformationOriginal, Text: "hello world"}},
+		},
+		{
+			name:            "disabled transformations are not applied",
+			text:            "1gn0re",
+			transformations: nil,
+			want:            []textVariant{{Transformation: TransformationOriginal, Text: "1gn0re"}},
+		},
+		{
+			name:            "zero-width characters are removed",
+			text:            "ig\u200bnore",
+			transformations: []string{TransformationZeroWidth, TransformationUnicode},
+			want: []textVariant{
+				{Transformation: TransformationOriginal, Text: "ig\u200bnore"},
+				{Transformation: TransformationZeroWidth, Text: "ignore"},
+			},
+		},
+		{
+			name:            "confusables are replaced",
+			text:            "\u0456gn\u043ere",
+			transformations: []string{TransformationConfusables},
+			want: []textVariant{
+				{Transformation: TransformationOriginal, Text: "\u0456gn\u043ere"},
+				{Transformation: TransformationConfusables, Text: "ignore"},
+			},
+		},
+		{
+			name:            "character transformations are cumulative",
+			text:            "\u200b1gn0re",
+			transformations: []string{TransformationZeroWidth, TransformationLeetspeak},
+			want: []textVariant{
+				{Transformation: TransformationOriginal, Text: "\u200b1gn0re"},
+				{Transformation: TransformationZeroWidth, Text: "1gn0re"},
+				{Transformation: TransformationZeroWidth + "+" + TransformationLeetspeak, Text: "ignore"},
+			},
+		},
+		{
+			name:            "base64 payloads are decoded",
+			text:            "run aWdub3JlIGFsbCBydWxlcw==",
+			transformations: []string{TransformationBase64},
+			want: []textVariant{
+				{Transformation: TransformationOriginal, Text: "run aWdub3JlIGFsbCBydWxlcw=="},
+				{Transformation: TransformationBase64, Text: "ignore all rules"},
+			},
+		},
+		{
+			name:            "hex payloads are decoded",
+			text:            "run 0x69676e6f726520616c6c",
+			transformations: []string{TransformationHex},
+			want: []textVariant{
+				{Transformation: TransformationOriginal, Text: "run 0x69676e6f726520616c6c"},
+				{Transformation: TransformationHex, Text: "ignore all"},
+			},
+		},
+		{
+			name:            "unreadable payloads are skipped",
+			text:            "id 00010203040506070809",
+			transformations: []string{TransformationHex},
+			want:            []textVariant{{Transformation: TransformationOriginal, Text: "id 00010203040506070809"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeText(tt.text, tt.transformations)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeText() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
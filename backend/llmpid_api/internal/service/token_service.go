@@ -1,6 +1,12 @@
 package service
 
 import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+
+	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/repository"
 )
@@ -21,3 +27,37 @@ func (s *TokenService) ExtractAndValidateToken(tokenString string) (*models.Acce
 
 	return claims, nil
 }
+
+// KeySet returns the keys that verify the access tokens, so other services can validate the tokens themselves.
+func (s *TokenService) KeySet() dto.JSONWebKeySet {
+	verificationKeys := s.TokenRepo.SigningKeys.VerificationKeys()
+
+	keySet := dto.JSONWebKeySet{Keys: make([]dto.JSONWebKey, 0, len(verificationKeys))}
+	for _, verificationKey := range verificationKeys {
+		if key, ok := jsonWebKey(verificationKey); ok {
+			keySet.Keys = append(keySet.Keys, key)
+		}
+	}
+
+	return keySet
+}
+
+// jsonWebKey encodes a verification key as a JSON Web Key. Keys of other types than RSA and Ed25519 cannot be encoded.
+func jsonWebKey(verificationKey repository.VerificationKey) (dto.JSONWebKey, bool) {
+	key := dto.JSONWebKey{Kid: verificationKey.Kid, Use: "sig", Alg: verificationKey.Algorithm}
+
+	switch publicKey := verificationKey.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return dto.JSONWebKey{}, false
+	}
+
+	return key, true
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"llm-promp-inj.api/internal/dto"
+	"llm-promp-inj.api/internal/repository"
+)
+
+func TestJSONWebKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    repository.VerificationKey
+		want   dto.JSONWebKey
+		wantOK bool
+	}{
+		{
+			name: "Ed25519",
+			key:  repository.VerificationKey{Kid: "ed", Algorithm: repository.SigningAlgorithmEdDSA, PublicKey: ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))},
+			want: dto.JSONWebKey{
+				Kty: "OKP", Kid: "ed", Use: "sig", Alg: repository.SigningAlgorithmEdDSA,
+				Crv: "Ed25519", X: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+			},
+			wantOK: true,
+		},
+		{
+			name: "RSA",
+			key:  repository.VerificationKey{Kid: "rsa", Algorithm: repository.SigningAlgorithmRS256, PublicKey: &rsa.PublicKey{N: big.NewInt(0xabcd), E: 65537}},
+			want: dto.JSONWebKey{
+				Kty: "RSA", Kid: "rsa", Use: "sig", Alg: repository.SigningAlgorithmRS256,
+				N: "q80", E: "AQAB",
+			},
+			wantOK: true,
+		},
+		{
+			name:   "unsupported key type",
+			key:    repository.VerificationKey{Kid: "other", PublicKey: "not a key"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := jsonWebKey(tt.key)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("jsonWebKey() = %+v, %t, want %+v, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,31 +1,162 @@
 package service
 
 import (
+	"errors"
+
+	"llm-promp-inj.api/internal/dto"
 	"llm-promp-inj.api/internal/models"
 	"llm-promp-inj.api/internal/repository"
 )
 
 type UserService struct {
-	UserRepo   *repository.UserRepository
-	CryptoRepo *repository.CryptoRepository
+	UserRepo    *repository.UserRepository
+	CryptoRepo  *repository.CryptoRepository
+	SessionRepo *repository.SessionRepository
+	RoleRepo    *repository.RoleRepository
 }
 
-func NewUserService(userRepo *repository.UserRepository, cryptoRepo *repository.CryptoRepository) *UserService {
-	return &UserService{UserRepo: userRepo, CryptoRepo: cryptoRepo}
+func NewUserService(userRepo *repository.UserRepository, cryptoRepo *repository.CryptoRepository, sessionRepo *repository.SessionRepository, roleRepo *repository.RoleRepository) *UserService {
+	return &UserService{UserRepo: userRepo, CryptoRepo: cryptoRepo, SessionRepo: sessionRepo, RoleRepo: roleRepo}
 }
 
-func (s *UserService) Create(username string, password string, role string) (models.User, error) {
+// Create creates a user in the tenant.
+func (s *UserService) Create(tenantID uint, createRequest dto.CreateUserRequest) (models.User, error) {
 	var user models.User
 
-	passwordHash, err := s.CryptoRepo.HashSaltString(password)
+	if err := dto.Validate(createRequest); err != nil {
+		return user, err
+	}
+	if err := s.checkRole(createRequest.Role); err != nil {
+		return user, err
+	}
+
+	// Usernames are shared with external systems, which are stored as users too.
+	if _, err := s.UserRepo.SelectUserByUsername(createRequest.Username); err == nil {
+		return user, errors.New("username is already taken")
+	}
+
+	passwordHash, err := s.CryptoRepo.HashSaltString(createRequest.Password)
 	if err != nil {
 		return user, err
 	}
 
-	user, err = s.UserRepo.InsertUser(username, passwordHash, role)
+	user, err = s.UserRepo.InsertUser(tenantID, createRequest.Username, passwordHash, createRequest.Role)
 	if err != nil {
 		return user, err
 	}
 
 	return user, nil
 }
+
+// List returns all user accounts of the tenant. External systems are managed through their own API and are not listed.
+func (s *UserService) List(tenantID uint) ([]models.User, error) {
+	return s.UserRepo.SelectUsersExceptRole(tenantID, "ext_sys")
+}
+
+// Get returns a user of the tenant.
+func (s *UserService) Get(tenantID uint, username string) (models.User, error) {
+	user, err := s.UserRepo.SelectTenantUserByUsername(tenantID, username)
+	if err != nil || user.Role == "ext_sys" {
+		return models.User{}, errors.New("user not found")
+	}
+
+	return user, nil
+}
+
+// SetDisabled disables or enables a user. Disabled users cannot log in and their sessions are revoked.
+func (s *UserService) SetDisabled(tenantID uint, username string, disabled bool) (models.User, error) {
+	user, err := s.Get(tenantID, username)
+	if err != nil {
+		return user, err
+	}
+
+	if err := s.UserRepo.UpdateDisabledByUsername(tenantID, username, disabled); err != nil {
+		return user, err
+	}
+	if disabled {
+		s.revokeSessions(user)
+	}
+
+	return s.Get(tenantID, username)
+}
+
+func (s *UserService) Delete(tenantID uint, username string) error {
+	user, err := s.Get(tenantID, username)
+	if err != nil {
+		return err
+	}
+
+	if err := s.UserRepo.DeleteUserByUsername(tenantID, username); err != nil {
+		return err
+	}
+	s.revokeSessions(user)
+
+	return nil
+}
+
+// ResetPassword sets a new password for a user without requiring the old one and revokes the sessions of the user.
+func (s *UserService) ResetPassword(tenantID uint, username string, resetRequest dto.ResetPasswordRequest) error {
+	if err := dto.Validate(resetRequest); err != nil {
+		return err
+	}
+
+	user, err := s.Get(tenantID, username)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := s.CryptoRepo.HashSaltString(resetRequest.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.UserRepo.UpdatePasswordHashByUserID(tenantID, user.ID, passwordHash); err != nil {
+		return err
+	}
+	s.revokeSessions(user)
+
+	return nil
+}
+
+// ChangeRole changes the role of a user. The sessions of the user are revoked, since their tokens carry the old role.
+func (s *UserService) ChangeRole(tenantID uint, username string, roleRequest dto.ChangeRoleRequest) (models.User, error) {
+	if err := dto.Validate(roleRequest); err != nil {
+		return models.User{}, err
+	}
+	if err := s.checkRole(roleRequest.Role); err != nil {
+		return models.User{}, err
+	}
+
+	user, err := s.Get(tenantID, username)
+	if err != nil {
+		return user, err
+	}
+
+	if err := s.UserRepo.UpdateRoleByUsername(tenantID, username, roleRequest.Role); err != nil {
+		return user, err
+	}
+	s.revokeSessions(user)
+
+	return s.Get(tenantID, username)
+}
+
+// checkRole ensures that a role can be assigned to users. The role of external systems is reserved for their own API.
+func (s *UserService) checkRole(roleName string) error {
+	if roleName == models.RoleExtSys {
+		return errors.New("role is reserved for external systems")
+	}
+
+	role, err := s.RoleRepo.SelectRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.New("role not found")
+	}
+
+	return nil
+}
+
+func (s *UserService) revokeSessions(user models.User) {
+	s.SessionRepo.DeleteSessionBySub(user.TenantID, s.CryptoRepo.GenerateJWTSubject(user.Username, user.ID))
+}
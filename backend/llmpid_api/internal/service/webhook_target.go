@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// webhookLookupTimeout bounds the resolution of the host of a webhook when it is registered.
+const webhookLookupTimeout = 5 * time.Second
+
+var errWebhookAddressNotAllowed = errors.New("webhook URL must resolve to a public address")
+
+// reservedNetworks are non-public networks that are not covered by the classification methods of net.IP.
+var reservedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// isPublicAddress reports whether webhooks may be delivered to the address, i.e. it is not a loopback, private,
+// link-local, unspecified, multicast or otherwise reserved address.
+func isPublicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range reservedNetworks {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAllowedWebhookHost reports whether the host is on the allow-list, so it may resolve to any address (e.g. an internal service).
+func isAllowedWebhookHost(host string, allowedHosts []string) bool {
+	for _, allowedHost := range allowedHosts {
+		if strings.EqualFold(host, allowedHost) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkWebhookURL validates a webhook URL and makes sure that its host only resolves to public addresses, unless the host is on the allow-list.
+func checkWebhookURL(ctx context.Context, webhookURL string, allowedHosts []string) error {
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return errors.New("invalid webhook URL")
+	}
+
+	host := parsedURL.Hostname()
+	if isAllowedWebhookHost(host, allowedHosts) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookLookupTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return errors.New("webhook host cannot be resolved")
+	}
+	for _, addr := range addrs {
+		if !isPublicAddress(addr.IP) {
+			return errWebhookAddressNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// newWebhookClient returns an HTTP client that refuses to connect to non-public addresses, unless the host is on the allow-list.
+// The address is checked when the connection is made, so a host that resolves to another address than on registration
+// (DNS rebinding) is rejected as well. Proxies are not used, since they would hide the address of the webhook.
+func newWebhookClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	guardedDialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return errWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && isAllowedWebhookHost(host, allowedHosts) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guardedDialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
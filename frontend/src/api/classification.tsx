@@ -4,13 +4,14 @@ import api from '@/utils/axiosInstance';
 export const fetchClassification = async ({
   queryKey,
 }: {
-  queryKey: [string, number, number, string];
+  queryKey: [string, string, number, string];
 }) => {
-  const [, page, limit, sort] = queryKey;
+  const [, cursor, limit, sort] = queryKey;
   try {
     const res = await api.get(`/classification/logs`, {
       params: {
-        page,
+        pagination: 'cursor',
+        cursor: cursor || undefined,
         limit,
         sortBy: sort,
       },
@@ -41,10 +41,11 @@ function Dashboard() {
     "asc" | "desc" | null
   >(null);
   const [page, setPage] = useState(1);
+  const [cursor, setCursor] = useState("");
   const [limit, setLimit] = useState(10);
 
   const { data, error, isLoading } = useQuery({
-    queryKey: ["classification", page, limit, sort],
+    queryKey: ["classification", cursor, limit, sort],
     queryFn: fetchClassification,
   });
 
@@ -67,6 +68,7 @@ function Dashboard() {
       prevValues.current.sort !== sort
     ) {
       setPage(1);
+      setCursor("");
     }
 
     prevValues.current = { limit, sort };
@@ -80,7 +82,7 @@ function Dashboard() {
     if (!data) {
       return [];
     }
-    const items = data.slice();
+    const items = data.data.slice();
     if (sourceSortDirection) {
       items.sort((a: any, b: any) => {
         const aName = a.source_name ?? "";
@@ -317,8 +319,11 @@ function Dashboard() {
         </Flex>
         <Flex my={5} justify="center" gap={4}>
           <Button
-            onClick={() => setPage((prev) => Math.max(prev - 1, 1))}
-            disabled={page === 1}
+            onClick={() => {
+              setCursor(data.prev_cursor);
+              setPage((prev) => Math.max(prev - 1, 1));
+            }}
+            disabled={!data || !data.prev_cursor}
           >
             Prev
           </Button>
@@ -326,8 +331,11 @@ function Dashboard() {
             Page {page}
           </Text>
           <Button
-            onClick={() => setPage((prev) => prev + 1)}
-            disabled={!data || data.length < limit}
+            onClick={() => {
+              setCursor(data.next_cursor);
+              setPage((prev) => prev + 1);
+            }}
+            disabled={!data || !data.next_cursor}
           >
             Next
           </Button>
@@ -1,23 +1,78 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+-- The default tenant (ID 1) operates the deployment.
+INSERT INTO tenants (name) VALUES ('default');
+
 CREATE TABLE IF NOT EXISTS classification_logs (
     id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
     source_name VARCHAR(64),
     request_text TEXT NOT NULL,
     result VARCHAR(32),
+    score DOUBLE PRECISION,
+    threshold DOUBLE PRECISION,
+    model_version VARCHAR(64),
+    action VARCHAR(16),
+    correlation_id VARCHAR(64),
+    role VARCHAR(32),
+    conversation_id VARCHAR(128),
+    source_ref TEXT,
+    tags JSONB NOT NULL DEFAULT '[]',
+    transformation VARCHAR(128),
+    matched_rules JSONB NOT NULL DEFAULT '[]',
+    chunk_count INT NOT NULL DEFAULT 1,
+    trigger_span_start INT,
+    trigger_span_end INT,
+    cache_hit BOOLEAN NOT NULL DEFAULT FALSE,
+    member_verdicts JSONB NOT NULL DEFAULT '[]',
+    shadow_result VARCHAR(32),
+    shadow_score DOUBLE PRECISION,
+    shadow_model_version VARCHAR(64),
+    label VARCHAR(16),
+    reviewer VARCHAR(64),
+    review_note TEXT,
+    reviewed_at TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT NULL
+    updated_at TIMESTAMP DEFAULT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', request_text)) STORED
 );
 
+CREATE INDEX IF NOT EXISTS classification_logs_conversation_id_idx ON classification_logs (conversation_id);
+CREATE INDEX IF NOT EXISTS classification_logs_tags_idx ON classification_logs USING GIN (tags);
+CREATE INDEX IF NOT EXISTS classification_logs_tenant_created_at_id_idx ON classification_logs (tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS classification_logs_search_vector_idx ON classification_logs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS classification_logs_unlabeled_idx ON classification_logs (tenant_id, created_at) WHERE label IS NULL;
+CREATE INDEX IF NOT EXISTS classification_logs_review_queue_idx ON classification_logs (tenant_id, (ABS(score - threshold)), id DESC) WHERE label IS NULL;
+
 CREATE TABLE IF NOT EXISTS users (
     id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
     username VARCHAR(64) NOT NULL UNIQUE, 
     password_hash TEXT NOT NULL,
     role VARCHAR(32) NOT NULL,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(32) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    permissions JSONB NOT NULL DEFAULT '[]',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT NULL
 );
 
 CREATE TABLE IF NOT EXISTS sessions (
     id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
     sub TEXT NOT NULL,
     session_id VARCHAR(64) NOT NULL UNIQUE,
     expires_at BIGINT NOT NULL,
@@ -25,7 +80,148 @@ CREATE TABLE IF NOT EXISTS sessions (
     updated_at TIMESTAMP DEFAULT NULL
 );
 
-INSERT INTO users (username, password_hash, role) VALUES (
+CREATE TABLE IF NOT EXISTS classification_policies (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
+    source_name VARCHAR(64) NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    flag_threshold DOUBLE PRECISION NOT NULL,
+    block_threshold DOUBLE PRECISION NOT NULL,
+    fail_mode VARCHAR(16) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS classification_policies_tenant_source_name_idx ON classification_policies (tenant_id, source_name);
+
+CREATE TABLE IF NOT EXISTS classification_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
+    source_name VARCHAR(64),
+    request_text TEXT NOT NULL,
+    role VARCHAR(32),
+    conversation_id VARCHAR(128),
+    source_ref TEXT,
+    tags JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(16) NOT NULL,
+    log_id BIGINT REFERENCES classification_logs(id) ON DELETE SET NULL,
+    error TEXT,
+    webhook_status VARCHAR(16),
+    webhook_attempts INT NOT NULL DEFAULT 0,
+    webhook_next_attempt_at TIMESTAMP,
+    locked_by VARCHAR(128),
+    lease_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS classification_jobs_status_idx ON classification_jobs (status);
+CREATE INDEX IF NOT EXISTS classification_jobs_webhook_due_idx ON classification_jobs (webhook_next_attempt_at) WHERE webhook_status = 'pending';
+CREATE INDEX IF NOT EXISTS classification_jobs_lease_idx ON classification_jobs (lease_until) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
+    source_name VARCHAR(64) NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS conversation_risks (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants (id),
+    source_name VARCHAR(64) NOT NULL,
+    conversation_id VARCHAR(128) NOT NULL,
+    turns INT NOT NULL DEFAULT 0,
+    flagged_turns INT NOT NULL DEFAULT 0,
+    max_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    decayed_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    action VARCHAR(16) NOT NULL,
+    last_turn_at TIMESTAMP,
+    last_flagged_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL,
+    UNIQUE (source_name, conversation_id)
+);
+
+CREATE TABLE IF NOT EXISTS classification_cache_entries (
+    key VARCHAR(64) PRIMARY KEY,
+    model_version VARCHAR(64) NOT NULL,
+    result VARCHAR(32) NOT NULL,
+    score DOUBLE PRECISION,
+    threshold DOUBLE PRECISION,
+    member_verdicts JSONB NOT NULL DEFAULT '[]',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS classification_cache_entries_expires_at_idx ON classification_cache_entries (expires_at);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id BIGSERIAL PRIMARY KEY,
+    kid VARCHAR(64) UNIQUE NOT NULL,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    retires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS detection_rules (
+    id VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    type VARCHAR(16) NOT NULL,
+    pattern TEXT NOT NULL DEFAULT '',
+    phrases JSONB NOT NULL DEFAULT '[]',
+    score DOUBLE PRECISION NOT NULL,
+    action VARCHAR(16) NOT NULL DEFAULT '',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NULL
+);
+
+-- Migrations upgrade databases created by earlier versions of this file. Their changes are already part of the schema above.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001_classification_scores'),
+    ('002_classification_policies'),
+    ('003_correlation_ids'),
+    ('004_classification_jobs'),
+    ('005_text_chunks'),
+    ('006_transformations'),
+    ('007_matched_rules'),
+    ('008_fail_modes'),
+    ('009_classification_cache'),
+    ('010_shadow_results'),
+    ('011_member_verdicts'),
+    ('012_classification_context'),
+    ('013_conversation_risks'),
+    ('014_log_search'),
+    ('015_log_cursors'),
+    ('016_labels'),
+    ('017_disabled_users'),
+    ('018_roles'),
+    ('019_tenants'),
+    ('020_signing_keys'),
+    ('021_webhook_deliveries'),
+    ('022_detection_rules'),
+    ('023_classification_cache_expiry'),
+    ('024_conversation_flagged_turns'),
+    ('025_review_queue'),
+    ('026_account_permission'),
+    ('027_job_leases'),
+    ('028_detection_rule_actions'),
+    ('029_policy_source_per_tenant');
+
+INSERT INTO users (tenant_id, username, password_hash, role) VALUES (
+    1,
     'admin',
     '$argon2id$v=19$m=65536,t=1,p=10$ff+Is1j1GoKrkiiYvLLyGQ$xKmunDT6s3/xoa2+ajvex9tFDNdDLN5aSOFgVzqNMWo',
     'admin'